@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/config"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/worker"
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  partitions   create upcoming clicks partitions and apply the retention policy
+  redis-keys   rename redis keys to their cluster mode names before moving to Redis Cluster
+`
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatalf(".env file not loaded: %v", err)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{}))
+
+	switch os.Args[1] {
+	case "partitions":
+		partitions(os.Args[2:], logger)
+	case "redis-keys":
+		redisKeys(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+func partitions(args []string, logger *slog.Logger) {
+	cfg := config.NewPartitionConfig()
+
+	fs := flag.NewFlagSet("partitions", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	ahead := fs.Int("ahead", cfg.Ahead, "number of future monthly partitions to create")
+	retention := fs.Int("retention", cfg.RetentionMonths, "months of raw clicks to keep, 0 keeps everything")
+	mode := fs.String("mode", cfg.RetentionMode, "what to do with expired partitions: drop or archive")
+	fs.Parse(args)
+
+	if *mode != config.RetentionDrop && *mode != config.RetentionArchive {
+		log.Fatalf("invalid -mode %q, expected %s or %s", *mode, config.RetentionDrop, config.RetentionArchive)
+	}
+
+	db, err := config.NewPostgresDB(config.NewPostgresConfig())
+	if err != nil {
+		log.Fatalf("connect to postgres: %v", err)
+	}
+	defer db.Close()
+
+	clicks := repository.NewClickRepository(logger, db)
+	maintainer := worker.NewPartitionMaintainer(clicks, worker.PartitionOptions{
+		Ahead:     *ahead,
+		Retention: *retention,
+		Archive:   *mode == config.RetentionArchive,
+		DryRun:    *dryRun,
+	}, logger)
+
+	report, err := maintainer.RunOnce(time.Now())
+	if report != nil {
+		for _, name := range report.Created {
+			fmt.Println("created ", name)
+		}
+		for _, name := range report.Archived {
+			fmt.Println("archived", name)
+		}
+		for _, name := range report.Dropped {
+			fmt.Println("dropped ", name)
+		}
+		if report.DefaultExpired > 0 {
+			fmt.Printf("expired  %d clicks in the default partition\n", report.DefaultExpired)
+		}
+	}
+	if err != nil {
+		log.Fatalf("maintain partitions: %v", err)
+	}
+}
+
+func redisKeys(args []string) {
+	fs := flag.NewFlagSet("redis-keys", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	fs.Parse(args)
+
+	cfg := config.NewRedisConfig()
+	if cfg.Mode == config.RedisModeCluster {
+		log.Fatalf("redis-keys must run against the single node or sentinel deployment holding the untagged keys")
+	}
+
+	client, err := config.NewRedisClient(cfg)
+	if err != nil {
+		log.Fatalf("connect to redis: %v", err)
+	}
+	defer client.Close()
+
+	res, err := repository.MigrateRedisKeys(context.Background(), client, *dryRun)
+	if res != nil {
+		fmt.Println("renamed", res.Renamed)
+		fmt.Println("merged ", res.Merged)
+		for _, key := range res.Skipped {
+			fmt.Println("skipped", key)
+		}
+	}
+	if err != nil {
+		log.Fatalf("migrate redis keys: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/config"
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/worker"
+	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatalf(".env file not loaded: %v", err)
+	}
+
+	metrics.InitMetrics()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
+
+	dbConfig := config.NewDatabaseConfig()
+	dbConnections, err := config.NewDatabaseConnections(dbConfig)
+	if err != nil {
+		log.Fatalf("initialize database connections: %v", err)
+	}
+	defer dbConnections.Close()
+
+	if dbConnections.Redis == nil {
+		log.Fatal("the click worker reads the click stream and requires REDIS_ENABLED")
+	}
+
+	metrics.InstrumentPostgres(dbConnections.Postgres)
+	metrics.InstrumentRedis(dbConnections.Redis)
+
+	analyticsConfig := config.NewAnalyticsConfig()
+	workerConfig := config.NewWorkerConfig()
+
+	stream := repository.NewClickStream(dbConnections.Redis, analyticsConfig.ClickStreamSize)
+	if dbConfig.Redis.HashTags() {
+		stream.WithHashTags()
+	}
+	clicks := repository.NewClickRepository(logger, dbConnections.Postgres)
+
+	consumer := worker.NewClickConsumer(stream, clicks, worker.Options{
+		Consumer:      workerConfig.Consumer,
+		BatchSize:     int64(workerConfig.BatchSize),
+		Block:         workerConfig.Block,
+		ClaimInterval: workerConfig.ClaimInterval,
+		ClaimMinIdle:  workerConfig.ClaimMinIdle,
+		MaxDeliveries: int64(workerConfig.MaxDeliveries),
+		RetryBackoff:  workerConfig.RetryBackoff,
+	}, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Addr:        workerConfig.MetricsAddr,
+		Handler:     mux,
+		ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ReadTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server", "error", err)
+		}
+	}()
+
+	go consumer.RunBacklogMetrics(ctx, workerConfig.BacklogInterval)
+
+	rollupConfig := config.NewRollupConfig()
+	if rollupConfig.Enabled {
+		rollups := worker.NewRollupJob(clicks, rollupConfig.Interval, rollupConfig.Settle, logger)
+		go rollups.Run(ctx)
+	}
+
+	partitionConfig := config.NewPartitionConfig()
+	if partitionConfig.Enabled {
+		partitions := worker.NewPartitionMaintainer(clicks, worker.PartitionOptions{
+			Ahead:     partitionConfig.Ahead,
+			Retention: partitionConfig.RetentionMonths,
+			Archive:   partitionConfig.RetentionMode == config.RetentionArchive,
+		}, logger)
+		go partitions.Run(ctx, partitionConfig.Interval)
+	}
+
+	logger.Info("click worker started", "consumer", workerConfig.Consumer)
+
+	if err := consumer.Run(ctx); err != nil {
+		logger.Error(err.Error())
+		log.Fatal(err)
+	}
+
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
+}
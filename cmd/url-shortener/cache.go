@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/cache"
+	"github.com/Vadim-Makhnev/url-shortener/internal/config"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+// newURLCache builds the short code cache selected by CACHE_BACKEND. client
+// is nil when Redis is disabled.
+func newURLCache(ctx context.Context, cfg *config.CacheConfig, client redis.UniversalClient, logger *slog.Logger) (service.Cache, error) {
+	switch cfg.Backend {
+	case config.CacheBackendRedis:
+		if client == nil {
+			return nil, errors.New("CACHE_BACKEND=redis requires REDIS_ENABLED")
+		}
+
+		redisCache := repository.NewRedisRepository(client, cfg.KeyPrefix)
+		if cfg.LegacyKeys {
+			redisCache.WithLegacyKeys()
+		}
+
+		var shared cache.Remote = redisCache
+		if cfg.BreakerFailures > 0 {
+			shared = cache.NewBreaker(shared, cfg.BreakerFailures, cfg.BreakerCooldown, logger)
+		}
+		if cfg.LocalSize <= 0 {
+			return shared, nil
+		}
+
+		invalidations := repository.NewCacheInvalidations(client, cfg.KeyPrefix)
+		tiered := cache.NewTiered(cache.NewLRU(cfg.LocalSize, cfg.LocalTTL), shared, invalidations, logger)
+		go tiered.Run(ctx)
+		return tiered, nil
+	case config.CacheBackendMemory:
+		return cache.NewMemory(cfg.MemorySize, cfg.MemoryTTL), nil
+	case config.CacheBackendNone:
+		return cache.Noop{}, nil
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q", cfg.Backend)
+	}
+}
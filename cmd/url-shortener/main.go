@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"flag"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/Vadim-Makhnev/url-shortener/internal/bloom"
 	"github.com/Vadim-Makhnev/url-shortener/internal/config"
+	"github.com/Vadim-Makhnev/url-shortener/internal/geoip"
 	"github.com/Vadim-Makhnev/url-shortener/internal/handler"
+	"github.com/Vadim-Makhnev/url-shortener/internal/healthcheck"
+	"github.com/Vadim-Makhnev/url-shortener/internal/live"
 	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/realip"
 	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/resolver"
+	"github.com/Vadim-Makhnev/url-shortener/internal/scanner"
 	"github.com/Vadim-Makhnev/url-shortener/internal/service"
+	"github.com/Vadim-Makhnev/url-shortener/internal/useragent"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type application struct {
-	handler *handler.URLHandler
+	handler    *handler.URLHandler
+	adminToken string
 }
 
 func main() {
@@ -39,19 +54,202 @@ func main() {
 	}
 	defer dbConnections.Close()
 
+	metrics.InstrumentPostgres(dbConnections.Postgres)
+	if dbConnections.Redis != nil {
+		metrics.InstrumentRedis(dbConnections.Redis)
+	}
+
 	postgres := repository.NewRepositoryPostgres(
 		logger,
 		dbConnections.Postgres,
 	)
 
-	redis := repository.NewRedisRepository(dbConnections.Redis)
+	scannerConfig := config.NewScannerConfig()
+	urlScanner := scanner.New(scannerConfig.RiskThreshold)
+
+	var urlResolver service.Resolver
+	resolverConfig := config.NewResolverConfig()
+	if resolverConfig.Enabled {
+		urlResolver = resolver.New(
+			resolverConfig.MaxHops,
+			resolverConfig.Timeout,
+			resolverConfig.MaxBodyBytes,
+			resolverConfig.AllowPrivate,
+			resolverConfig.SelfHosts...,
+		)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cacheConfig := config.NewCacheConfig()
+
+	urlCache, err := newURLCache(ctx, cacheConfig, dbConnections.Redis, logger)
+	if err != nil {
+		log.Fatalf("initialize cache: %v", err)
+	}
+
+	urlService := service.NewService(postgres, urlCache, urlScanner, urlResolver, logger)
+	urlService.WithNegativeCache(cacheConfig.NegativeTTL)
+	if cacheConfig.CodeFilter {
+		// New codes reach other instances through the shared cache and
+		// Pub/Sub announcements; a per-instance cache would answer them
+		// from a stale missing entry.
+		if cacheConfig.Backend != config.CacheBackendRedis {
+			log.Fatalf("CODE_FILTER_ENABLED requires CACHE_BACKEND=%s, got %q", config.CacheBackendRedis, cacheConfig.Backend)
+		}
+		announcements := repository.NewCodeAnnouncements(dbConnections.Redis, cacheConfig.KeyPrefix)
+		urlService.WithCodeFilter(bloom.New(cacheConfig.CodeFilterCapacity, cacheConfig.CodeFilterFalsePositiveRate), announcements)
+		go urlService.RunCodeFilter(ctx, cacheConfig.CodeFilterRebuildInterval)
+	}
+
+	if cacheConfig.Backend != config.CacheBackendNone && cacheConfig.WarmupLinks > 0 {
+		var order string
+		switch cacheConfig.WarmupOrder {
+		case config.WarmupOrderClicks:
+			order = repository.URLOrderClicks
+		case config.WarmupOrderRecent:
+			order = repository.URLOrderRecent
+		default:
+			log.Fatalf("unknown CACHE_WARMUP_ORDER %q", cacheConfig.WarmupOrder)
+		}
+
+		urlService.WithWarmup(service.WarmupOptions{
+			Links:     cacheConfig.WarmupLinks,
+			Order:     order,
+			BatchSize: cacheConfig.WarmupBatchSize,
+			Rate:      cacheConfig.WarmupRate,
+		})
+		if cacheConfig.WarmupOnStart {
+			if err := urlService.StartCacheWarmup(ctx); err != nil {
+				logger.Warn("cache warmup", "error", err)
+			}
+		}
+	}
+
+	analyticsConfig := config.NewAnalyticsConfig()
+	if dbConnections.Redis == nil {
+		if changed := analyticsConfig.WithoutRedis(); len(changed) > 0 {
+			logger.Warn("REDIS_ENABLED is false, settings that need Redis were switched to postgres or off", "settings", changed)
+		}
+	}
+	var counterBuffer service.CounterBuffer = service.NewMemoryCounterBuffer()
+	if analyticsConfig.CounterBuffer == config.CounterBufferRedis {
+		counterBuffer = repository.NewRedisCounterBuffer(dbConnections.Redis)
+	}
+
+	clickCounter := service.NewClickCounter(counterBuffer, postgres, analyticsConfig.ClickFlushInterval, logger)
+	counterDone := make(chan struct{})
+	go func() {
+		clickCounter.Run(ctx)
+		close(counterDone)
+	}()
+
+	clicks := repository.NewClickRepository(logger, dbConnections.Postgres)
+
+	var clickSink service.ClickSink = clicks
+	if analyticsConfig.ClickSink == config.ClickSinkStream {
+		stream := repository.NewClickStream(dbConnections.Redis, analyticsConfig.ClickStreamSize)
+		if dbConfig.Redis.HashTags() {
+			stream.WithHashTags()
+		}
+		clickSink = stream
+	}
+
+	analyticsService := service.NewAnalyticsService(postgres, clicks, clickSink, clickCounter, logger)
+
+	if analyticsConfig.TrackingQueue <= 0 {
+		log.Fatalf("CLICK_TRACKING_QUEUE must be positive, got %d", analyticsConfig.TrackingQueue)
+	}
+	analyticsService.WithAsyncTracking(analyticsConfig.TrackingQueue)
+	trackingDone := make(chan struct{})
+	go func() {
+		analyticsService.RunTracking(analyticsConfig.TrackingWorkers)
+		close(trackingDone)
+	}()
+
+	if analyticsConfig.UniqueVisitors {
+		salt := analyticsConfig.VisitorSalt
+		if salt == "" {
+			salt = rand.Text()
+			logger.Warn("VISITOR_SALT is not set, unique visitor estimates will not be consistent across restarts or instances")
+		}
+		visitors := repository.NewVisitorRepository(dbConnections.Redis, analyticsConfig.VisitorRetention)
+		if dbConfig.Redis.HashTags() {
+			visitors.WithHashTags()
+		}
+		analyticsService.WithUniqueVisitors(visitors, salt)
+	}
+
+	if analyticsConfig.Leaderboard {
+		leaderboard := repository.NewLeaderboardRepository(dbConnections.Redis)
+		if dbConfig.Redis.HashTags() {
+			leaderboard.WithHashTags()
+		}
+		analyticsService.WithLeaderboard(leaderboard)
+	}
+
+	classifier, err := useragent.New(analyticsConfig.UserAgentRules)
+	if err != nil {
+		log.Fatalf("load user agent rules: %v", err)
+	}
+	analyticsService.WithUserAgentClassifier(classifier)
+
+	metricsConfig := config.NewMetricsConfig()
+	hotLinks := metrics.NewHotLinks(metricsConfig.HotLinksTopK, metricsConfig.HotLinksCapacity)
+	prometheus.MustRegister(hotLinks)
+	go hotLinks.Run(ctx, metricsConfig.HotLinksHalfLife)
+	analyticsService.WithHotLinks(hotLinks)
+
+	geoIPConfig := config.NewGeoIPConfig()
+	if geoIPConfig.DatabasePath != "" {
+		locator, err := geoip.Open(geoIPConfig.DatabasePath)
+		if err != nil {
+			log.Fatalf("open geoip database: %v", err)
+		}
+		defer locator.Close()
+		analyticsService.WithGeoIP(locator)
+	}
+
+	clientIPs, err := realip.New(geoIPConfig.TrustedProxies)
+	if err != nil {
+		log.Fatalf("parse trusted proxies: %v", err)
+	}
+
+	urlHandler := handler.NewHandler(urlService, analyticsService).WithClientIPResolver(clientIPs)
+
+	if analyticsConfig.LiveStream {
+		feed := repository.NewClickFeed(dbConnections.Redis)
+		hub := live.NewHub(analyticsConfig.LiveStreamBuffer, logger)
+		go hub.Run(ctx, feed)
+		analyticsService.WithClickPublisher(feed)
+		urlHandler.WithLiveFeed(hub)
+	}
 
-	urlService := service.NewService(postgres, redis, logger)
+	authConfig := config.NewAuthConfig()
+	if authConfig.AdminToken == "" {
+		logger.Warn("ADMIN_TOKEN is not set, admin endpoints are disabled")
+	}
 
-	urlHandler := handler.NewHandler(urlService)
+	healthCheckConfig := config.NewHealthCheckConfig()
+	if healthCheckConfig.Enabled {
+		checker := healthcheck.New(postgres, urlCache, healthcheck.Options{
+			Interval:     healthCheckConfig.Interval,
+			RecheckAfter: healthCheckConfig.RecheckAfter,
+			BatchSize:    healthCheckConfig.BatchSize,
+			Concurrency:  healthCheckConfig.Concurrency,
+			HostDelay:    healthCheckConfig.HostDelay,
+			Timeout:      healthCheckConfig.Timeout,
+			DisableAfter: healthCheckConfig.DisableAfter,
+			MaxRedirects: healthCheckConfig.MaxRedirects,
+			AllowPrivate: healthCheckConfig.AllowPrivate,
+		}, logger)
+		go checker.Run(ctx)
+	}
 
 	app := application{
-		handler: urlHandler,
+		handler:    urlHandler,
+		adminToken: authConfig.AdminToken,
 	}
 
 	srv := &http.Server{
@@ -63,7 +261,38 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	err = srv.ListenAndServe()
-	logger.Error(err.Error())
-	log.Fatal(err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		logger.Error(err.Error())
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("shutdown", "error", err)
+	}
+
+	// Queued clicks still add to the click counts, so they are drained
+	// before the final flush.
+	analyticsService.StopTracking()
+	select {
+	case <-trackingDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("click tracking queue not drained before shutdown")
+	}
+
+	<-counterDone
+	if err := clickCounter.Flush(shutdownCtx); err != nil {
+		logger.Error("final click count flush", "error", err)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -9,11 +10,16 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Vadim-Makhnev/url-shortener/internal/bloom"
 	"github.com/Vadim-Makhnev/url-shortener/internal/handler"
 	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/scanner"
 	"github.com/Vadim-Makhnev/url-shortener/internal/service"
 	"github.com/Vadim-Makhnev/url-shortener/internal/test/testhelper"
+	"github.com/Vadim-Makhnev/url-shortener/internal/useragent"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,16 +27,39 @@ func TestMain_WithTestDB(t *testing.T) {
 	connections := testhelper.NewTestDatabaseConnections(t)
 	defer connections.Close()
 
-	testhelper.CleanupTestDatabase(t, connections, "urls")
+	testhelper.CleanupTestDatabase(t, connections, "urls", "clicks", "click_rollups_hourly", "click_rollups_daily")
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	postgres := repository.NewRepositoryPostgres(logger, connections.Postgres)
-	redis := repository.NewRedisRepository(connections.Redis)
-	urlService := service.NewService(postgres, redis, logger)
-	urlHandler := handler.NewHandler(urlService)
+	redis := repository.NewRedisRepository(connections.Redis, "test:url:")
+	urlService := service.NewService(postgres, redis, scanner.New(scanner.DefaultThreshold), nil, logger).
+		WithNegativeCache(time.Minute).
+		WithWarmup(service.WarmupOptions{Links: 100, Order: repository.URLOrderClicks, BatchSize: 50}).
+		WithCodeFilter(bloom.New(1000, 0.01), repository.NewCodeAnnouncements(connections.Redis, "test:url:"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go urlService.RunCodeFilter(ctx, time.Hour)
+	for deadline := time.Now().Add(5 * time.Second); !urlService.CodeFilterReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("code filter not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	clickCounter := service.NewClickCounter(service.NewMemoryCounterBuffer(), postgres, time.Second, logger)
+	clicks := repository.NewClickRepository(logger, connections.Postgres)
+	classifier, err := useragent.New("")
+	if err != nil {
+		t.Fatalf("load user agent rules: %v", err)
+	}
+	analyticsService := service.NewAnalyticsService(postgres, clicks, clicks, clickCounter, logger).
+		WithUniqueVisitors(repository.NewVisitorRepository(connections.Redis, time.Hour), "test-salt").
+		WithUserAgentClassifier(classifier).
+		WithLeaderboard(repository.NewLeaderboardRepository(connections.Redis))
+	urlHandler := handler.NewHandler(urlService, analyticsService)
 
 	app := application{
-		handler: urlHandler,
+		handler:    urlHandler,
+		adminToken: "test-token",
 	}
 
 	router := app.routes()
@@ -82,6 +111,260 @@ func TestMain_WithTestDB(t *testing.T) {
 		}
 	})
 
+	t.Run("LinkStats", func(t *testing.T) {
+		createReq := map[string]string{"url": "https://example.org/stats"}
+		jsonData, _ := json.Marshal(createReq)
+
+		req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var createResponse map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &createResponse)
+
+		shortURL := createResponse["short_url"].(string)
+		shortCode := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+		for i := 0; i < 3; i++ {
+			req = httptest.NewRequest("GET", "/"+shortCode, nil)
+			req.Header.Set("Referer", "https://www.news.example.com/article")
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+		}
+
+		req = httptest.NewRequest("GET", "/"+shortCode, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		err := clickCounter.Flush(context.Background())
+		assert.NoError(t, err)
+
+		req = httptest.NewRequest("GET", "/api/urls", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var list []map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &list)
+		for _, url := range list {
+			if url["short_url"] == shortURL {
+				assert.Equal(t, float64(4), url["click_count"])
+			}
+		}
+
+		req = httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats?bucket=hour", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var stats map[string]any
+		err = json.Unmarshal(rr.Body.Bytes(), &stats)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(3), stats["total"])
+		assert.Equal(t, float64(1), stats["unique_visitors"])
+		assert.Len(t, stats["series"], 7*24+1)
+
+		referrers := stats["referrers"].([]any)
+		assert.Equal(t, "news.example.com", referrers[0].(map[string]any)["value"])
+
+		browsers := stats["browsers"].([]any)
+		assert.Equal(t, "Chrome", browsers[0].(map[string]any)["value"])
+
+		// Rolled up clicks must not be counted twice.
+		_, err = clicks.Rollup(0)
+		assert.NoError(t, err)
+
+		req = httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		err = json.Unmarshal(rr.Body.Bytes(), &stats)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(3), stats["total"])
+
+		req = httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats?include_bots=true", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		err = json.Unmarshal(rr.Body.Bytes(), &stats)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(4), stats["total"])
+
+		req = httptest.NewRequest("GET", "/api/urls/missing/stats", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("TopLinks", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/links/top?window=hour", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var top map[string]any
+		err := json.Unmarshal(rr.Body.Bytes(), &top)
+		assert.NoError(t, err)
+		assert.Equal(t, "hour", top["window"])
+		assert.NotEmpty(t, top["links"])
+
+		req = httptest.NewRequest("GET", "/api/links/trending?window=day", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		req = httptest.NewRequest("GET", "/api/links/top?window=year", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("LiveClicksRequiresToken", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/live/clicks", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		req = httptest.NewRequest("GET", "/api/live/clicks?token=wrong", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		// The live feed is not wired in this test, so an authorized request
+		// reaches the handler and gets 404.
+		req = httptest.NewRequest("GET", "/api/live/clicks", nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("FlaggedURLShowsInterstitial", func(t *testing.T) {
+		createReq := map[string]string{"url": "https://xn--pypal-4ve.com/signin"}
+		jsonData, _ := json.Marshal(createReq)
+
+		req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var createResponse map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &createResponse)
+		assert.Equal(t, true, createResponse["flagged"])
+
+		shortURL := createResponse["short_url"].(string)
+		shortCode := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+		req = httptest.NewRequest("GET", "/"+shortCode, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Continue anyway")
+
+		req = httptest.NewRequest("GET", "/"+shortCode+"?confirm=1", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusFound, rr.Code)
+		assert.Equal(t, "https://xn--pypal-4ve.com/signin", rr.Header().Get("Location"))
+	})
+
+	t.Run("UnknownAndDeletedURL", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/zzzzzz", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		createReq := map[string]string{"url": "https://example.org"}
+		jsonData, _ := json.Marshal(createReq)
+
+		req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var createResponse map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &createResponse)
+		shortURL := createResponse["short_url"].(string)
+		shortCode := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+		// Created after the filter was built, so it must have been added.
+		req = httptest.NewRequest("GET", "/"+shortCode, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusFound, rr.Code)
+
+		req = httptest.NewRequest("DELETE", "/api/urls/"+shortCode, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		req = httptest.NewRequest("DELETE", "/api/urls/"+shortCode, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		req = httptest.NewRequest("GET", "/"+shortCode, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		_, err := redis.Get(context.Background(), shortCode)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+
+		req = httptest.NewRequest("DELETE", "/api/urls/"+shortCode, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("LegacyKeysNeverCacheMissingUnderBareKey", func(t *testing.T) {
+		ctx := context.Background()
+		legacyCache := repository.NewRedisRepository(connections.Redis, "test:legacy:").WithLegacyKeys()
+		legacyService := service.NewService(postgres, legacyCache, scanner.New(scanner.DefaultThreshold), nil, logger).
+			WithNegativeCache(time.Minute)
+
+		url, err := legacyService.ShortenURL("https://example.com/legacy")
+		assert.NoError(t, err)
+		defer connections.Redis.Del(ctx, url.ShortCode, "test:legacy:"+url.ShortCode)
+
+		// Instances from before the key prefix read the bare destination.
+		val, err := connections.Redis.Get(ctx, url.ShortCode).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/legacy", val)
+
+		assert.NoError(t, legacyService.DeleteURL(url.ShortCode))
+
+		// They would redirect to an empty value, so the key must be gone.
+		_, err = connections.Redis.Get(ctx, url.ShortCode).Result()
+		assert.ErrorIs(t, err, goredis.Nil)
+		_, err = legacyCache.Get(ctx, url.ShortCode)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+
+		_, err = legacyService.GetOriginalURL("legacyunknown")
+		assert.Error(t, err)
+		_, err = connections.Redis.Get(ctx, "legacyunknown").Result()
+		assert.ErrorIs(t, err, goredis.Nil)
+	})
+
+	t.Run("CacheWarmupRequiresToken", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/cache/warm", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		req = httptest.NewRequest("POST", "/api/cache/warm", nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+
 	t.Run("GetAllURLs", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/api/urls", nil)
 		rr := httptest.NewRecorder()
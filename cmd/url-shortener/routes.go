@@ -3,12 +3,16 @@ package main
 import (
 	"net/http"
 
+	"github.com/Vadim-Makhnev/url-shortener/internal/handler"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func (app *application) routes() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(handler.InstrumentRoutes)
+	r.NotFoundHandler = handler.InstrumentRoutes(http.NotFoundHandler())
+	r.MethodNotAllowedHandler = handler.InstrumentRoutes(http.HandlerFunc(handler.MethodNotAllowed))
 
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -18,6 +22,20 @@ func (app *application) routes() *mux.Router {
 	api := r.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/shorten", app.handler.ShortenURL).Methods("POST")
 	api.HandleFunc("/urls", app.handler.GetURLs).Methods("GET")
+	api.HandleFunc("/urls/{shortCode}/stats", app.handler.Stats).Methods("GET")
+	api.HandleFunc("/links/top", app.handler.TopLinks).Methods("GET")
+	api.HandleFunc("/links/trending", app.handler.TrendingLinks).Methods("GET")
+	api.HandleFunc("/reports/broken-links", app.handler.BrokenLinks).Methods("GET")
+
+	adminRoutes := api.NewRoute().Subrouter()
+	adminRoutes.Use(handler.RequireToken(app.adminToken))
+	adminRoutes.HandleFunc("/urls/{shortCode}", app.handler.DeleteURL).Methods("DELETE")
+	adminRoutes.HandleFunc("/cache/warm", app.handler.WarmCache).Methods("POST")
+
+	liveRoutes := api.PathPrefix("/live").Subrouter()
+	liveRoutes.Use(handler.RequireStreamToken(app.adminToken))
+	liveRoutes.HandleFunc("/clicks", app.handler.LiveClicks).Methods("GET")
+	liveRoutes.HandleFunc("/clicks/{shortCode}", app.handler.LiveClicks).Methods("GET")
 
 	r.Handle("/metrics", promhttp.Handler())
 
@@ -0,0 +1,119 @@
+// Package bloom implements a Bloom filter of strings, used to reject short
+// codes that were never created without a database lookup.
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+)
+
+// Filter is a Bloom filter safe for concurrent use. Test never returns false
+// for a string that was added, and returns true for a string that was not
+// added with roughly the false positive rate the filter was sized for.
+// Strings cannot be removed; Rebuild the filter to forget them.
+type Filter struct {
+	mu   sync.RWMutex
+	bits []uint64
+	// next is the bit set being filled by Rebuild. Strings added while a
+	// rebuild is running go into both sets so none are lost by the swap.
+	next []uint64
+	m    uint64
+	k    int
+
+	rebuild sync.Mutex
+	seed1   maphash.Seed
+	seed2   maphash.Seed
+}
+
+// New sizes a filter for n strings at the false positive rate p.
+func New(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := max(1, int(math.Round(float64(m)/float64(n)*math.Ln2)))
+
+	return &Filter{
+		bits:  make([]uint64, m/64),
+		m:     m,
+		k:     k,
+		seed1: maphash.MakeSeed(),
+		seed2: maphash.MakeSeed(),
+	}
+}
+
+func (f *Filter) Add(s string) {
+	h1, h2 := f.hash(s)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.set(f.bits, h1, h2)
+	if f.next != nil {
+		f.set(f.next, h1, h2)
+	}
+}
+
+func (f *Filter) Test(s string) bool {
+	h1, h2 := f.hash(s)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for i := range f.k {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Rebuild replaces the contents of the filter with the strings fill adds.
+// The filter keeps answering from the old contents until fill returns, and
+// keeps them if fill fails.
+func (f *Filter) Rebuild(fill func(add func(string)) error) error {
+	f.rebuild.Lock()
+	defer f.rebuild.Unlock()
+
+	f.mu.Lock()
+	f.next = make([]uint64, len(f.bits))
+	f.mu.Unlock()
+
+	err := fill(func(s string) {
+		h1, h2 := f.hash(s)
+
+		f.mu.Lock()
+		f.set(f.next, h1, h2)
+		f.mu.Unlock()
+	})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.bits = f.next
+	}
+	f.next = nil
+
+	return err
+}
+
+func (f *Filter) set(bits []uint64, h1, h2 uint64) {
+	for i := range f.k {
+		bit := (h1 + uint64(i)*h2) % f.m
+		bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// hash returns the two base hashes combined into k indexes by double
+// hashing. h2 is forced odd so the indexes do not collapse onto one bit.
+func (f *Filter) hash(s string) (uint64, uint64) {
+	return maphash.String(f.seed1, s), maphash.String(f.seed2, s) | 1
+}
@@ -0,0 +1,82 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestFilter_NoFalseNegatives(t *testing.T) {
+	f := New(1000, 0.01)
+
+	for i := range 1000 {
+		f.Add("code" + strconv.Itoa(i))
+	}
+
+	for i := range 1000 {
+		if !f.Test("code" + strconv.Itoa(i)) {
+			t.Fatalf("added string code%d not found", i)
+		}
+	}
+}
+
+func TestFilter_FalsePositiveRate(t *testing.T) {
+	f := New(10000, 0.01)
+
+	for i := range 10000 {
+		f.Add("code" + strconv.Itoa(i))
+	}
+
+	positives := 0
+	for i := range 10000 {
+		if f.Test("other" + strconv.Itoa(i)) {
+			positives++
+		}
+	}
+
+	// 1% expected, leave room for an unlucky seed.
+	if positives > 300 {
+		t.Errorf("false positives = %d of 10000, want about 100", positives)
+	}
+}
+
+func TestFilter_Rebuild(t *testing.T) {
+	f := New(100, 0.01)
+	f.Add("deleted")
+
+	err := f.Rebuild(func(add func(string)) error {
+		add("kept")
+		f.Add("created during rebuild")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+
+	if !f.Test("kept") {
+		t.Error("string added by fill not found")
+	}
+	if !f.Test("created during rebuild") {
+		t.Error("string added during rebuild was lost")
+	}
+	if f.Test("deleted") {
+		t.Error("string missing from rebuild still found")
+	}
+}
+
+func TestFilter_RebuildFailureKeepsContents(t *testing.T) {
+	f := New(100, 0.01)
+	f.Add("existing")
+
+	err := f.Rebuild(func(add func(string)) error {
+		add("partial")
+		return errors.New("query failed")
+	})
+	if err == nil {
+		t.Fatal("Rebuild error = nil, want fill error")
+	}
+
+	if !f.Test("existing") {
+		t.Error("failed rebuild dropped existing contents")
+	}
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker is a circuit breaker in front of the shared cache. After a number
+// of consecutive failures it opens and fails every call immediately with
+// repository.ErrCacheUnavailable, so lookups go straight to postgres instead
+// of waiting on an unhealthy Redis. After the cooldown a single call is let
+// through as a probe; its outcome closes the breaker or opens it again.
+// Misses and missing entries are not failures.
+type Breaker struct {
+	next      Remote
+	threshold int
+	cooldown  time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(next Remote, threshold int, cooldown time.Duration, logger *slog.Logger) *Breaker {
+	metrics.CacheBreakerState.Set(float64(breakerClosed))
+
+	return &Breaker{
+		next:      next,
+		threshold: threshold,
+		cooldown:  cooldown,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+func (b *Breaker) Set(ctx context.Context, shortCode, originalURL string) error {
+	return b.do(shortCode, func() error {
+		return b.next.Set(ctx, shortCode, originalURL)
+	})
+}
+
+func (b *Breaker) Fill(ctx context.Context, shortCode, originalURL string) error {
+	return b.do(shortCode, func() error {
+		return b.next.Fill(ctx, shortCode, originalURL)
+	})
+}
+
+func (b *Breaker) Get(ctx context.Context, shortCode string) (string, error) {
+	var val string
+	err := b.do(shortCode, func() error {
+		var err error
+		val, err = b.next.Get(ctx, shortCode)
+		return err
+	})
+	return val, err
+}
+
+func (b *Breaker) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	return b.do(shortCode, func() error {
+		return b.next.SetMissing(ctx, shortCode, ttl)
+	})
+}
+
+func (b *Breaker) Delete(ctx context.Context, shortCode string) error {
+	return b.do(shortCode, func() error {
+		return b.next.Delete(ctx, shortCode)
+	})
+}
+
+func (b *Breaker) do(shortCode string, call func() error) error {
+	if !b.allow() {
+		metrics.CacheBreakerRejected.Inc()
+		return fmt.Errorf("cache: circuit open: %s: %w", shortCode, repository.ErrCacheUnavailable)
+	}
+
+	err := call()
+	b.record(err)
+	return err
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.transition(breakerHalfOpen, nil)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) record(err error) {
+	failed := err != nil &&
+		!errors.Is(err, repository.ErrCacheMiss) &&
+		!errors.Is(err, repository.ErrNotFound)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open(err)
+			return
+		}
+		b.failures = 0
+		b.transition(breakerClosed, nil)
+	case breakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open(err)
+		}
+	}
+}
+
+func (b *Breaker) open(err error) {
+	b.openedAt = b.now()
+	b.transition(breakerOpen, err)
+}
+
+func (b *Breaker) transition(to breakerState, err error) {
+	from := b.state
+	b.state = to
+
+	metrics.CacheBreakerState.Set(float64(to))
+	metrics.CacheBreakerTransitions.WithLabelValues(to.String()).Inc()
+
+	switch to {
+	case breakerOpen:
+		b.logger.Warn("cache: circuit breaker opened, serving from postgres", "from", from.String(),
+			"failures", b.failures, "cooldown", b.cooldown, "error", err)
+	case breakerClosed:
+		b.logger.Info("cache: circuit breaker closed", "from", from.String())
+	}
+}
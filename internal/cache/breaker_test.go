@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type flakyRemote struct {
+	fakeRemote
+	err   error
+	calls int
+}
+
+func (f *flakyRemote) Get(ctx context.Context, shortCode string) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.fakeRemote.Get(ctx, shortCode)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	remote := &flakyRemote{fakeRemote: fakeRemote{values: map[string]string{}}}
+	b := NewBreaker(remote, 3, 10*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Misses are not failures.
+	for range 5 {
+		b.Get(ctx, "abc123")
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state after misses = %s, want closed", b.state)
+	}
+
+	remote.err = errors.New("connection refused")
+	for range 3 {
+		b.Get(ctx, "abc123")
+	}
+	if b.state != breakerOpen {
+		t.Fatalf("state after 3 failures = %s, want open", b.state)
+	}
+
+	calls := remote.calls
+	if _, err := b.Get(ctx, "abc123"); !errors.Is(err, repository.ErrCacheUnavailable) {
+		t.Errorf("Get while open error = %v, want ErrCacheUnavailable", err)
+	}
+	if remote.calls != calls {
+		t.Error("open breaker called the remote")
+	}
+
+	// The probe after the cooldown fails and opens the breaker again.
+	now = now.Add(10 * time.Second)
+	b.Get(ctx, "abc123")
+	if b.state != breakerOpen || remote.calls != calls+1 {
+		t.Fatalf("state after failed probe = %s, calls = %d", b.state, remote.calls-calls)
+	}
+
+	remote.err = nil
+	now = now.Add(10 * time.Second)
+	if _, err := b.Get(ctx, "abc123"); !errors.Is(err, repository.ErrCacheMiss) {
+		t.Errorf("probe error = %v, want ErrCacheMiss", err)
+	}
+	if b.state != breakerClosed {
+		t.Errorf("state after successful probe = %s, want closed", b.state)
+	}
+}
@@ -0,0 +1,5 @@
+// Package cache holds the pluggable implementations of the short code
+// cache: Memory for a single instance, Noop to disable caching, Tiered to
+// keep an in-process LRU in front of the shared Redis cache, and Breaker to
+// stop calling the shared cache while it is unavailable.
+package cache
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, time.Minute)
+
+	c.Add("a", "1")
+	c.Add("b", "2")
+	c.Get("a")
+	c.Add("c", "3")
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Errorf("Get(a) = %q, %v, want 1, true", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != "3" {
+		t.Errorf("Get(c) = %q, %v, want 3, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestLRU_Expires(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", "1")
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry still returned after its TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len = %d, want expired entry removed", c.Len())
+	}
+}
+
+func TestLRU_RemoveAndPurge(t *testing.T) {
+	c := NewLRU(10, time.Minute)
+
+	c.Add("a", "1")
+	c.Add("b", "")
+	c.Remove("a")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("removed entry still returned")
+	}
+	if v, ok := c.Get("b"); !ok || v != "" {
+		t.Errorf("Get(b) = %q, %v, want empty value, true", v, ok)
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len after Purge = %d", c.Len())
+	}
+}
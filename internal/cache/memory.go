@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+// Memory is a process-local cache for single instance deployments that run
+// without Redis. Entries are not shared, so it must not be used when several
+// instances serve the same links.
+type Memory struct {
+	lru *LRU
+}
+
+func NewMemory(size int, ttl time.Duration) *Memory {
+	return &Memory{
+		lru: NewLRU(size, ttl),
+	}
+}
+
+func (m *Memory) Set(ctx context.Context, shortCode, originalURL string) error {
+	m.lru.Add(shortCode, originalURL)
+	return nil
+}
+
+func (m *Memory) Fill(ctx context.Context, shortCode, originalURL string) error {
+	if _, ok := m.lru.Get(shortCode); !ok {
+		m.lru.Add(shortCode, originalURL)
+	}
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, shortCode string) (string, error) {
+	val, ok := m.lru.Get(shortCode)
+	switch {
+	case !ok:
+		return "", fmt.Errorf("cache: key not found: %s: %w", shortCode, repository.ErrCacheMiss)
+	case val == "":
+		return "", fmt.Errorf("cache: key cached as missing: %s: %w", shortCode, repository.ErrNotFound)
+	}
+	return val, nil
+}
+
+func (m *Memory) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	m.lru.AddWithTTL(shortCode, "", ttl)
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, shortCode string) error {
+	m.lru.Remove(shortCode)
+	return nil
+}
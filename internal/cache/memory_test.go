@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+func TestMemory_MissingEntryExpiresSeparately(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	m := NewMemory(10, 24*time.Hour)
+	m.lru.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	m.Set(ctx, "abc123", "https://example.com")
+	m.SetMissing(ctx, "zzzzzz", time.Minute)
+
+	if _, err := m.Get(ctx, "zzzzzz"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := m.Get(ctx, "zzzzzz"); !errors.Is(err, repository.ErrCacheMiss) {
+		t.Errorf("Get(expired missing) error = %v, want ErrCacheMiss", err)
+	}
+	if val, err := m.Get(ctx, "abc123"); err != nil || val != "https://example.com" {
+		t.Errorf("Get = %q, %v", val, err)
+	}
+}
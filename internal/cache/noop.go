@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+// Noop disables caching: every lookup misses and goes to postgres.
+type Noop struct{}
+
+func (Noop) Set(ctx context.Context, shortCode, originalURL string) error {
+	return nil
+}
+
+func (Noop) Fill(ctx context.Context, shortCode, originalURL string) error {
+	return nil
+}
+
+func (Noop) Get(ctx context.Context, shortCode string) (string, error) {
+	return "", fmt.Errorf("cache: disabled: %s: %w", shortCode, repository.ErrCacheMiss)
+}
+
+func (Noop) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	return nil
+}
+
+func (Noop) Delete(ctx context.Context, shortCode string) error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+const invalidationsRetry = 5 * time.Second
+
+// Remote is the shared cache tier.
+type Remote interface {
+	Set(ctx context.Context, shortCode, originalURL string) error
+	Fill(ctx context.Context, shortCode, originalURL string) error
+	Get(ctx context.Context, shortCode string) (string, error)
+	SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error
+	Delete(ctx context.Context, shortCode string) error
+}
+
+type Invalidations interface {
+	Publish(ctx context.Context, shortCode string) error
+	Subscribe(ctx context.Context, handle func(shortCode string), reset func()) error
+}
+
+// Tiered keeps recently read short codes in process in front of the shared
+// cache. Writes go to the shared cache; links that are replaced or deleted
+// are evicted from every instance through invalidations, and the local TTL
+// bounds staleness when an invalidation is lost.
+type Tiered struct {
+	local         *LRU
+	remote        Remote
+	invalidations Invalidations
+	retry         time.Duration
+	logger        *slog.Logger
+}
+
+func NewTiered(local *LRU, remote Remote, invalidations Invalidations, logger *slog.Logger) *Tiered {
+	return &Tiered{
+		local:         local,
+		remote:        remote,
+		invalidations: invalidations,
+		retry:         invalidationsRetry,
+		logger:        logger,
+	}
+}
+
+// Run applies invalidations from other instances until ctx is done. A
+// failed or dropped subscription is retried, and the local tier is purged
+// every time it is established, since invalidations sent meanwhile are lost.
+func (t *Tiered) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := t.invalidations.Subscribe(ctx, func(shortCode string) {
+			metrics.LocalCacheInvalidations.Inc()
+			t.local.Remove(shortCode)
+		}, t.local.Purge)
+		if err != nil {
+			t.logger.Error("cache: subscribe to invalidations", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(t.retry):
+		}
+	}
+}
+
+// Get answers from the local tier when possible. Missing entries are cached
+// locally as well, as an empty value, like in the shared tier.
+func (t *Tiered) Get(ctx context.Context, shortCode string) (string, error) {
+	if val, ok := t.local.Get(shortCode); ok {
+		metrics.LocalCacheRequests.WithLabelValues("hit").Inc()
+		if val == "" {
+			return "", fmt.Errorf("cache: key cached as missing: %s: %w", shortCode, repository.ErrNotFound)
+		}
+		return val, nil
+	}
+	metrics.LocalCacheRequests.WithLabelValues("miss").Inc()
+
+	val, err := t.remote.Get(ctx, shortCode)
+	switch {
+	case err == nil:
+		t.local.Add(shortCode, val)
+	case errors.Is(err, repository.ErrNotFound):
+		t.local.Add(shortCode, "")
+	}
+	return val, err
+}
+
+func (t *Tiered) Set(ctx context.Context, shortCode, originalURL string) error {
+	if err := t.remote.Set(ctx, shortCode, originalURL); err != nil {
+		return err
+	}
+	t.invalidate(ctx, shortCode)
+	return nil
+}
+
+func (t *Tiered) Fill(ctx context.Context, shortCode, originalURL string) error {
+	return t.remote.Fill(ctx, shortCode, originalURL)
+}
+
+// SetMissing is not broadcast: it follows a failed lookup, and other
+// instances cannot hold a destination for a code that does not exist.
+func (t *Tiered) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	if err := t.remote.SetMissing(ctx, shortCode, ttl); err != nil {
+		return err
+	}
+	t.local.Add(shortCode, "")
+	return nil
+}
+
+func (t *Tiered) Delete(ctx context.Context, shortCode string) error {
+	if err := t.remote.Delete(ctx, shortCode); err != nil {
+		return err
+	}
+	t.invalidate(ctx, shortCode)
+	return nil
+}
+
+func (t *Tiered) invalidate(ctx context.Context, shortCode string) {
+	t.local.Remove(shortCode)
+	if err := t.invalidations.Publish(ctx, shortCode); err != nil {
+		t.logger.Warn("cache: publish invalidation", "short_code", shortCode, "error", err)
+	}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type fakeRemote struct {
+	values map[string]string
+	gets   int
+}
+
+func (f *fakeRemote) Set(ctx context.Context, shortCode, originalURL string) error {
+	f.values[shortCode] = originalURL
+	return nil
+}
+
+func (f *fakeRemote) Fill(ctx context.Context, shortCode, originalURL string) error {
+	return f.Set(ctx, shortCode, originalURL)
+}
+
+func (f *fakeRemote) Get(ctx context.Context, shortCode string) (string, error) {
+	f.gets++
+	val, ok := f.values[shortCode]
+	switch {
+	case !ok:
+		return "", repository.ErrCacheMiss
+	case val == "":
+		return "", repository.ErrNotFound
+	}
+	return val, nil
+}
+
+func (f *fakeRemote) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	return f.Set(ctx, shortCode, "")
+}
+
+func (f *fakeRemote) Delete(ctx context.Context, shortCode string) error {
+	delete(f.values, shortCode)
+	return nil
+}
+
+// fakeInvalidations ends the first subscriptions with errs, a nil entry
+// standing for a dropped connection, then stays subscribed until ctx is done.
+type fakeInvalidations struct {
+	errs       []error
+	calls      int
+	published  []string
+	handle     func(string)
+	reset      func()
+	subscribed chan struct{}
+}
+
+func (f *fakeInvalidations) Publish(ctx context.Context, shortCode string) error {
+	f.published = append(f.published, shortCode)
+	return nil
+}
+
+func (f *fakeInvalidations) Subscribe(ctx context.Context, handle func(string), reset func()) error {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+
+	f.handle, f.reset = handle, reset
+	reset()
+	close(f.subscribed)
+	<-ctx.Done()
+	return nil
+}
+
+// runTiered runs tiered until the test ends and waits for it to subscribe.
+func runTiered(t *testing.T, tiered *Tiered, invalidations *fakeInvalidations) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tiered.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	select {
+	case <-invalidations.subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("tiered cache did not subscribe to invalidations")
+	}
+}
+
+func newTestTiered(t *testing.T) (*Tiered, *fakeRemote, *fakeInvalidations) {
+	remote := &fakeRemote{values: map[string]string{"abc123": "https://example.com"}}
+	invalidations := &fakeInvalidations{subscribed: make(chan struct{})}
+	tiered := NewTiered(NewLRU(10, time.Minute), remote, invalidations, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	runTiered(t, tiered, invalidations)
+	return tiered, remote, invalidations
+}
+
+func TestTiered_GetUsesLocalTier(t *testing.T) {
+	tiered, remote, _ := newTestTiered(t)
+	ctx := context.Background()
+
+	for range 3 {
+		val, err := tiered.Get(ctx, "abc123")
+		if err != nil || val != "https://example.com" {
+			t.Fatalf("Get = %q, %v", val, err)
+		}
+	}
+	for range 3 {
+		if _, err := tiered.Get(ctx, "zzzzzz"); !errors.Is(err, repository.ErrCacheMiss) {
+			t.Fatalf("Get(unknown) error = %v, want ErrCacheMiss", err)
+		}
+	}
+
+	// Misses are not cached locally, only values and missing entries.
+	if remote.gets != 4 {
+		t.Errorf("remote gets = %d, want 4", remote.gets)
+	}
+
+	tiered.SetMissing(ctx, "zzzzzz", time.Minute)
+	if _, err := tiered.Get(ctx, "zzzzzz"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+	if remote.gets != 4 {
+		t.Errorf("missing entry was not served locally, remote gets = %d", remote.gets)
+	}
+}
+
+func TestTiered_Invalidation(t *testing.T) {
+	tiered, remote, invalidations := newTestTiered(t)
+	ctx := context.Background()
+
+	tiered.Get(ctx, "abc123")
+	tiered.Delete(ctx, "abc123")
+
+	if len(invalidations.published) != 1 || invalidations.published[0] != "abc123" {
+		t.Errorf("published = %v, want [abc123]", invalidations.published)
+	}
+	if _, err := tiered.Get(ctx, "abc123"); !errors.Is(err, repository.ErrCacheMiss) {
+		t.Errorf("Get after Delete error = %v, want ErrCacheMiss", err)
+	}
+
+	// Another instance replaces the destination and broadcasts it.
+	remote.values["abc123"] = "https://example.org"
+	tiered.local.Add("abc123", "https://example.com")
+	invalidations.handle("abc123")
+
+	if val, _ := tiered.Get(ctx, "abc123"); val != "https://example.org" {
+		t.Errorf("Get after invalidation = %q, want the new destination", val)
+	}
+
+	invalidations.reset()
+	if tiered.local.Len() != 0 {
+		t.Errorf("local entries after resubscribe = %d, want 0", tiered.local.Len())
+	}
+}
+
+func TestTiered_ResubscribesAndPurges(t *testing.T) {
+	remote := &fakeRemote{values: map[string]string{}}
+	invalidations := &fakeInvalidations{
+		errs:       []error{errors.New("connection refused"), nil},
+		subscribed: make(chan struct{}),
+	}
+	tiered := NewTiered(NewLRU(10, time.Minute), remote, invalidations, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	tiered.retry = time.Millisecond
+
+	// Cached while no invalidations were received.
+	tiered.local.Add("abc123", "https://stale.example")
+
+	runTiered(t, tiered, invalidations)
+
+	if invalidations.calls != 3 {
+		t.Errorf("subscribed %d times, want 3", invalidations.calls)
+	}
+	if tiered.local.Len() != 0 {
+		t.Errorf("local entries after resubscribe = %d, want 0", tiered.local.Len())
+	}
+}
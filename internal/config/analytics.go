@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	ClickSinkStream   = "stream"
+	ClickSinkPostgres = "postgres"
+
+	CounterBufferMemory = "memory"
+	CounterBufferRedis  = "redis"
+)
+
+type AnalyticsConfig struct {
+	ClickFlushInterval time.Duration
+	// CounterBuffer selects where click counts accumulate between flushes:
+	// in process, or in a Redis hash shared by all instances.
+	CounterBuffer string
+	// ClickSink selects where click events are written: the Redis stream
+	// drained by cmd/click-worker, or postgres directly.
+	ClickSink       string
+	ClickStreamSize int64
+	// TrackingQueue is the number of clicks buffered between redirects and
+	// the TrackingWorkers that write them to Redis and the click sink. It
+	// must be positive: clicks are never written on the redirect path.
+	TrackingQueue   int
+	TrackingWorkers int
+
+	UniqueVisitors bool
+	// VisitorSalt keys the visitor fingerprint hash. It must be secret and
+	// identical on all instances.
+	VisitorSalt      string
+	VisitorRetention time.Duration
+
+	// Leaderboard keeps time-bucketed click counts in Redis for the top and
+	// trending links endpoints.
+	Leaderboard bool
+
+	// LiveStream fans click events out to SSE clients through Redis Pub/Sub.
+	// LiveStreamBuffer is the number of events buffered per client before
+	// events are dropped for it.
+	LiveStream       bool
+	LiveStreamBuffer int
+
+	// UserAgentRules is a JSON rules file for user agent classification.
+	// The rules built into the binary are used when it is empty.
+	UserAgentRules string
+}
+
+func NewAnalyticsConfig() *AnalyticsConfig {
+	return &AnalyticsConfig{
+		ClickFlushInterval: getEnvDuration("CLICK_FLUSH_INTERVAL", 10*time.Second),
+		CounterBuffer:      getEnv("CLICK_COUNTER_BUFFER", CounterBufferMemory),
+		ClickSink:          getEnv("CLICK_SINK", ClickSinkStream),
+		ClickStreamSize:    int64(getEnvInt("CLICK_STREAM_MAX_LEN", 1000000)),
+		TrackingQueue:      getEnvInt("CLICK_TRACKING_QUEUE", 10000),
+		TrackingWorkers:    getEnvInt("CLICK_TRACKING_WORKERS", 4),
+		UniqueVisitors:     getEnvBool("UNIQUE_VISITORS_ENABLED", true),
+		VisitorSalt:        os.Getenv("VISITOR_SALT"),
+		VisitorRetention:   getEnvDuration("VISITOR_RETENTION", 400*24*time.Hour),
+		Leaderboard:        getEnvBool("LEADERBOARD_ENABLED", true),
+		LiveStream:         getEnvBool("LIVE_STREAM_ENABLED", true),
+		LiveStreamBuffer:   getEnvInt("LIVE_STREAM_BUFFER", 256),
+		UserAgentRules:     os.Getenv("UA_RULES_FILE"),
+	}
+}
+
+// WithoutRedis switches the analytics settings that need Redis to their
+// postgres or in-process alternatives, or off, and returns the names of the
+// settings it changed.
+func (c *AnalyticsConfig) WithoutRedis() []string {
+	var changed []string
+
+	if c.CounterBuffer == CounterBufferRedis {
+		c.CounterBuffer = CounterBufferMemory
+		changed = append(changed, "CLICK_COUNTER_BUFFER")
+	}
+	if c.ClickSink == ClickSinkStream {
+		c.ClickSink = ClickSinkPostgres
+		changed = append(changed, "CLICK_SINK")
+	}
+	if c.UniqueVisitors {
+		c.UniqueVisitors = false
+		changed = append(changed, "UNIQUE_VISITORS_ENABLED")
+	}
+	if c.Leaderboard {
+		c.Leaderboard = false
+		changed = append(changed, "LEADERBOARD_ENABLED")
+	}
+	if c.LiveStream {
+		c.LiveStream = false
+		changed = append(changed, "LIVE_STREAM_ENABLED")
+	}
+
+	return changed
+}
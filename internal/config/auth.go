@@ -0,0 +1,15 @@
+package config
+
+import "os"
+
+type AuthConfig struct {
+	// AdminToken is the bearer token for the live click stream and admin
+	// endpoints. They are disabled when it is empty.
+	AdminToken string
+}
+
+func NewAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import "time"
+
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+	CacheBackendNone   = "none"
+)
+
+const (
+	WarmupOrderClicks = "clicks"
+	WarmupOrderRecent = "recent"
+)
+
+type CacheConfig struct {
+	// Backend selects the short code cache: shared in Redis, in process for
+	// a single instance, or none. It defaults to memory when Redis is
+	// disabled.
+	Backend string
+	// KeyPrefix namespaces the Redis cache keys and invalidation channel.
+	KeyPrefix string
+	// LegacyKeys keeps the Redis cache shared with instances from before
+	// KeyPrefix during a rolling deploy: new links are also written as bare
+	// destinations under unprefixed keys, and deletes clear those keys.
+	// Turn it off once every instance runs a version that supports it.
+	LegacyKeys bool
+	MemorySize int
+	MemoryTTL  time.Duration
+	// LocalSize is the number of short codes kept in process in front of
+	// redis; 0 disables the local tier.
+	LocalSize int
+	// LocalTTL bounds how long an instance may serve a local entry after an
+	// invalidation was missed.
+	LocalTTL time.Duration
+	// BreakerFailures is the number of consecutive Redis failures that open
+	// the cache circuit breaker; 0 disables the breaker. BreakerCooldown is
+	// how long it stays open before a probe call is let through.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// NegativeTTL is how long unknown short codes are cached as missing;
+	// 0 disables negative caching.
+	NegativeTTL time.Duration
+	// CodeFilter keeps a Bloom filter of existing short codes in memory so
+	// unknown codes are rejected without a postgres lookup. New codes are
+	// announced to other instances over Redis, so it requires the redis
+	// backend.
+	CodeFilter bool
+	// CodeFilterCapacity is the number of codes the filter is sized for;
+	// beyond it the false positive rate grows.
+	CodeFilterCapacity          int
+	CodeFilterFalsePositiveRate float64
+	CodeFilterRebuildInterval   time.Duration
+
+	// WarmupLinks is the number of links preloaded into the cache at startup
+	// and on POST /api/cache/warm; 0 disables warm-up. WarmupOrder picks
+	// them by clicks or creation time, WarmupRate caps links per second.
+	WarmupLinks     int
+	WarmupOrder     string
+	WarmupBatchSize int
+	WarmupRate      int
+	WarmupOnStart   bool
+}
+
+func NewCacheConfig() *CacheConfig {
+	backend := CacheBackendRedis
+	if !getEnvBool("REDIS_ENABLED", true) {
+		backend = CacheBackendMemory
+	}
+
+	return &CacheConfig{
+		Backend:                     getEnv("CACHE_BACKEND", backend),
+		KeyPrefix:                   getEnv("CACHE_KEY_PREFIX", "url:"),
+		LegacyKeys:                  getEnvBool("CACHE_LEGACY_KEYS", true),
+		MemorySize:                  getEnvInt("CACHE_MEMORY_SIZE", 100000),
+		MemoryTTL:                   getEnvDuration("CACHE_MEMORY_TTL", 24*time.Hour),
+		LocalSize:                   getEnvInt("CACHE_LOCAL_SIZE", 10000),
+		LocalTTL:                    getEnvDuration("CACHE_LOCAL_TTL", 30*time.Second),
+		BreakerFailures:             getEnvInt("CACHE_BREAKER_FAILURES", 5),
+		BreakerCooldown:             getEnvDuration("CACHE_BREAKER_COOLDOWN", 10*time.Second),
+		NegativeTTL:                 getEnvDuration("CACHE_NEGATIVE_TTL", time.Minute),
+		CodeFilter:                  getEnvBool("CODE_FILTER_ENABLED", false),
+		CodeFilterCapacity:          getEnvInt("CODE_FILTER_CAPACITY", 1_000_000),
+		CodeFilterFalsePositiveRate: getEnvFloat("CODE_FILTER_FALSE_POSITIVE_RATE", 0.01),
+		CodeFilterRebuildInterval:   getEnvDuration("CODE_FILTER_REBUILD_INTERVAL", time.Hour),
+		WarmupLinks:                 getEnvInt("CACHE_WARMUP_LINKS", 10000),
+		WarmupOrder:                 getEnv("CACHE_WARMUP_ORDER", WarmupOrderClicks),
+		WarmupBatchSize:             getEnvInt("CACHE_WARMUP_BATCH_SIZE", 500),
+		WarmupRate:                  getEnvInt("CACHE_WARMUP_RATE", 2000),
+		WarmupOnStart:               getEnvBool("CACHE_WARMUP_ON_START", true),
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -15,6 +17,34 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 type DatabaseConfig struct {
 	Postgres *PostgresConfig
 	Redis    *RedisConfig
@@ -29,7 +59,8 @@ func NewDatabaseConfig() *DatabaseConfig {
 
 type DatabaseConnections struct {
 	Postgres *sql.DB
-	Redis    *redis.Client
+	// Redis is nil when Redis is disabled.
+	Redis redis.UniversalClient
 }
 
 func NewDatabaseConnections(config *DatabaseConfig) (*DatabaseConnections, error) {
@@ -38,16 +69,19 @@ func NewDatabaseConnections(config *DatabaseConfig) (*DatabaseConnections, error
 		return nil, fmt.Errorf("postgres: %w", err)
 	}
 
-	redisClient, err := NewRedisClient(config.Redis)
-	if err != nil {
-		postgresDB.Close()
-		return nil, fmt.Errorf("redis: %w", err)
+	connections := &DatabaseConnections{
+		Postgres: postgresDB,
 	}
 
-	return &DatabaseConnections{
-		Postgres: postgresDB,
-		Redis:    redisClient,
-	}, nil
+	if config.Redis.Enabled {
+		connections.Redis, err = NewRedisClient(config.Redis)
+		if err != nil {
+			postgresDB.Close()
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+	}
+
+	return connections, nil
 }
 
 func (dc *DatabaseConnections) Close() error {
@@ -57,8 +91,10 @@ func (dc *DatabaseConnections) Close() error {
 		errs = append(errs, fmt.Errorf("postgres close: %w", err))
 	}
 
-	if err := dc.Redis.Close(); err != nil {
-		errs = append(errs, fmt.Errorf("redis close: %w", err))
+	if dc.Redis != nil {
+		if err := dc.Redis.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis close: %w", err))
+		}
 	}
 
 	if len(errs) > 0 {
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+type GeoIPConfig struct {
+	// DatabasePath is a MaxMind-format MMDB file (GeoLite2/GeoIP2 City or
+	// Country). GeoIP enrichment is disabled when it is empty.
+	DatabasePath string
+	// TrustedProxies lists proxy CIDRs or addresses whose X-Forwarded-For
+	// header is honoured.
+	TrustedProxies []string
+}
+
+func NewGeoIPConfig() *GeoIPConfig {
+	var proxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		proxies = strings.Split(v, ",")
+	}
+
+	return &GeoIPConfig{
+		DatabasePath:   os.Getenv("GEOIP_DB_PATH"),
+		TrustedProxies: proxies,
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import "time"
+
+type HealthCheckConfig struct {
+	Enabled      bool
+	Interval     time.Duration
+	RecheckAfter time.Duration
+	BatchSize    int
+	Concurrency  int
+	HostDelay    time.Duration
+	Timeout      time.Duration
+	DisableAfter int
+	MaxRedirects int
+	AllowPrivate bool
+}
+
+func NewHealthCheckConfig() *HealthCheckConfig {
+	return &HealthCheckConfig{
+		Enabled:      getEnvBool("HEALTHCHECK_ENABLED", false),
+		Interval:     getEnvDuration("HEALTHCHECK_INTERVAL", 5*time.Minute),
+		RecheckAfter: getEnvDuration("HEALTHCHECK_RECHECK_AFTER", 24*time.Hour),
+		BatchSize:    getEnvInt("HEALTHCHECK_BATCH_SIZE", 500),
+		Concurrency:  getEnvInt("HEALTHCHECK_CONCURRENCY", 8),
+		HostDelay:    getEnvDuration("HEALTHCHECK_HOST_DELAY", time.Second),
+		Timeout:      getEnvDuration("HEALTHCHECK_TIMEOUT", 10*time.Second),
+		DisableAfter: getEnvInt("HEALTHCHECK_DISABLE_AFTER", 0),
+		MaxRedirects: getEnvInt("HEALTHCHECK_MAX_REDIRECTS", 10),
+		AllowPrivate: getEnvBool("HEALTHCHECK_ALLOW_PRIVATE", false),
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type MetricsConfig struct {
+	// HotLinksTopK is the number of hottest links exported as series.
+	HotLinksTopK int
+	// HotLinksCapacity bounds the number of links tracked in memory; larger
+	// values make the top K more accurate under a long tail of links.
+	HotLinksCapacity int
+	// HotLinksHalfLife is how often tracked click counts are halved.
+	HotLinksHalfLife time.Duration
+}
+
+func NewMetricsConfig() *MetricsConfig {
+	return &MetricsConfig{
+		HotLinksTopK:     getEnvInt("HOT_LINKS_TOP_K", 20),
+		HotLinksCapacity: getEnvInt("HOT_LINKS_CAPACITY", 1000),
+		HotLinksHalfLife: getEnvDuration("HOT_LINKS_HALF_LIFE", 10*time.Minute),
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+const (
+	RetentionDrop    = "drop"
+	RetentionArchive = "archive"
+)
+
+type PartitionConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Ahead is the number of future monthly partitions kept ready.
+	Ahead int
+	// RetentionMonths is how many past months of raw clicks are kept;
+	// 0 keeps everything. Rollups are not affected.
+	RetentionMonths int
+	// RetentionMode selects what happens to expired partitions: dropped, or
+	// detached and kept as clicks_archive_YYYYMM tables. Expired rows in the
+	// default partition are deleted, or moved to clicks_archive_default.
+	// Archives are never dropped by the service, so archive mode only frees
+	// space once an operator exports and drops them.
+	RetentionMode string
+}
+
+func NewPartitionConfig() *PartitionConfig {
+	return &PartitionConfig{
+		Enabled:         getEnvBool("CLICKS_PARTITIONS_ENABLED", true),
+		Interval:        getEnvDuration("CLICKS_PARTITIONS_INTERVAL", 12*time.Hour),
+		Ahead:           getEnvInt("CLICKS_PARTITIONS_AHEAD", 3),
+		RetentionMonths: getEnvInt("CLICKS_RETENTION_MONTHS", 0),
+		RetentionMode:   getEnv("CLICKS_RETENTION_MODE", RetentionDrop),
+	}
+}
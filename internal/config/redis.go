@@ -2,42 +2,182 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
 type RedisConfig struct {
-	Host     string
-	Port     string
+	// Enabled connects to Redis. Without it the cache falls back to memory
+	// and the features that need Redis are turned off.
+	Enabled bool
+	// Mode is single, sentinel or cluster. Single connects to Host:Port
+	// unless Addrs is set; sentinel and cluster need Addrs.
+	Mode string
+	Host string
+	Port string
+	// Addrs lists the sentinel addresses in sentinel mode and the seed nodes
+	// in cluster mode.
+	Addrs []string
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+	// Username enables ACL authentication; Password alone authenticates as
+	// the default user.
+	Username string
 	Password string
-	DB       int
+	// DB must be 0 in cluster mode.
+	DB int
+
+	TLS bool
+	// TLSCAFile verifies the server against this CA bundle instead of the
+	// system roots.
+	TLSCAFile     string
+	TLSServerName string
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
 }
 
 func NewRedisConfig() *RedisConfig {
+	var addrs []string
+	if v := os.Getenv("REDIS_ADDRS"); v != "" {
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
 	return &RedisConfig{
-		Host:     getEnv("REDIS_HOST", "localhost"),
-		Port:     getEnv("REDIS_PORT", "6379"),
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
+		Enabled:          getEnvBool("REDIS_ENABLED", true),
+		Mode:             getEnv("REDIS_MODE", RedisModeSingle),
+		Host:             getEnv("REDIS_HOST", "localhost"),
+		Port:             getEnv("REDIS_PORT", "6379"),
+		Addrs:            addrs,
+		MasterName:       os.Getenv("REDIS_SENTINEL_MASTER"),
+		SentinelUsername: os.Getenv("REDIS_SENTINEL_USERNAME"),
+		SentinelPassword: os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		Username:         os.Getenv("REDIS_USERNAME"),
+		Password:         os.Getenv("REDIS_PASSWORD"),
+		DB:               getEnvInt("REDIS_DB", 0),
+		TLS:              getEnvBool("REDIS_TLS", false),
+		TLSCAFile:        os.Getenv("REDIS_TLS_CA_FILE"),
+		TLSServerName:    os.Getenv("REDIS_TLS_SERVER_NAME"),
+		PoolSize:         getEnvInt("REDIS_POOL_SIZE", 0),
+		MinIdleConns:     getEnvInt("REDIS_MIN_IDLE_CONNS", 0),
+		DialTimeout:      getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		ReadTimeout:      getEnvDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		WriteTimeout:     getEnvDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+		PoolTimeout:      getEnvDuration("REDIS_POOL_TIMEOUT", 4*time.Second),
 	}
 }
 
-func NewRedisClient(config *RedisConfig) (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", config.Host, config.Port),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+// HashTags reports whether keys read together must share a hash tag. Only
+// Redis Cluster needs it; other modes keep the untagged key names, so
+// existing data stays readable.
+func (c *RedisConfig) HashTags() bool {
+	return c.Mode == RedisModeCluster
+}
+
+// NewRedisClient connects to a single node, a sentinel-monitored master or
+// a cluster depending on config.Mode. A zero PoolSize keeps the go-redis
+// default of 10 connections per CPU.
+func NewRedisClient(config *RedisConfig) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:            config.Addrs,
+		MasterName:       config.MasterName,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: config.SentinelPassword,
+		Username:         config.Username,
+		Password:         config.Password,
+		DB:               config.DB,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		DialTimeout:      config.DialTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		PoolTimeout:      config.PoolTimeout,
+	}
+
+	if config.TLS {
+		tlsConfig, err := newRedisTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	var client redis.UniversalClient
+	switch config.Mode {
+	case RedisModeSingle:
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = []string{fmt.Sprintf("%s:%s", config.Host, config.Port)}
+		}
+		client = redis.NewClient(opts.Simple())
+	case RedisModeSentinel:
+		if config.MasterName == "" || len(config.Addrs) == 0 {
+			return nil, errors.New("sentinel mode needs REDIS_SENTINEL_MASTER and REDIS_ADDRS")
+		}
+		client = redis.NewFailoverClient(opts.Failover())
+	case RedisModeCluster:
+		if len(config.Addrs) == 0 {
+			return nil, errors.New("cluster mode needs REDIS_ADDRS")
+		}
+		if config.DB != 0 {
+			return nil, errors.New("cluster mode only supports REDIS_DB=0")
+		}
+		client = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown REDIS_MODE %q", config.Mode)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("redis ping: %w", err)
 	}
 
 	return client, nil
 }
+
+func newRedisTLSConfig(config *RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.TLSServerName,
+	}
+
+	if config.TLSCAFile != "" {
+		pem, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read redis CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in redis CA file %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRedisClient_ModeValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RedisConfig
+		want   string
+	}{
+		{
+			name:   "sentinel without master",
+			config: RedisConfig{Mode: RedisModeSentinel, Addrs: []string{"sentinel:26379"}},
+			want:   "REDIS_SENTINEL_MASTER",
+		},
+		{
+			name:   "sentinel without addresses",
+			config: RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster"},
+			want:   "REDIS_ADDRS",
+		},
+		{
+			name:   "cluster without addresses",
+			config: RedisConfig{Mode: RedisModeCluster},
+			want:   "REDIS_ADDRS",
+		},
+		{
+			name:   "cluster with db",
+			config: RedisConfig{Mode: RedisModeCluster, Addrs: []string{"node:6379"}, DB: 1},
+			want:   "REDIS_DB=0",
+		},
+		{
+			name:   "unknown mode",
+			config: RedisConfig{Mode: "replica"},
+			want:   `unknown REDIS_MODE "replica"`,
+		},
+		{
+			name:   "missing CA file",
+			config: RedisConfig{Mode: RedisModeSingle, TLS: true, TLSCAFile: "/nonexistent/ca.pem"},
+			want:   "ca.pem",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewRedisClient(&tt.config)
+			if err == nil {
+				client.Close()
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisConfig_HashTags(t *testing.T) {
+	for mode, want := range map[string]bool{
+		RedisModeSingle:   false,
+		RedisModeSentinel: false,
+		RedisModeCluster:  true,
+	} {
+		if got := (&RedisConfig{Mode: mode}).HashTags(); got != want {
+			t.Errorf("HashTags() in %s mode = %v, want %v", mode, got, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"time"
+)
+
+type ResolverConfig struct {
+	Enabled      bool
+	MaxHops      int
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	AllowPrivate bool
+	SelfHosts    []string
+}
+
+func NewResolverConfig() *ResolverConfig {
+	var selfHosts []string
+	if baseURL, err := url.Parse(os.Getenv("BASE_URL")); err == nil && baseURL.Host != "" {
+		selfHosts = append(selfHosts, baseURL.Host)
+	}
+
+	return &ResolverConfig{
+		Enabled:      getEnvBool("RESOLVER_ENABLED", false),
+		MaxHops:      getEnvInt("RESOLVER_MAX_HOPS", 10),
+		Timeout:      getEnvDuration("RESOLVER_TIMEOUT", 5*time.Second),
+		MaxBodyBytes: int64(getEnvInt("RESOLVER_MAX_BODY_BYTES", 64<<10)),
+		AllowPrivate: getEnvBool("RESOLVER_ALLOW_PRIVATE", false),
+		SelfHosts:    selfHosts,
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+type RollupConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Settle is how long clicks are left alone after ingestion before they
+	// are rolled up, so inserts still in flight are not skipped.
+	Settle time.Duration
+}
+
+func NewRollupConfig() *RollupConfig {
+	return &RollupConfig{
+		Enabled:  getEnvBool("ROLLUP_ENABLED", true),
+		Interval: getEnvDuration("ROLLUP_INTERVAL", time.Minute),
+		Settle:   getEnvDuration("ROLLUP_SETTLE", 30*time.Second),
+	}
+}
@@ -0,0 +1,13 @@
+package config
+
+import "github.com/Vadim-Makhnev/url-shortener/internal/scanner"
+
+type ScannerConfig struct {
+	RiskThreshold int
+}
+
+func NewScannerConfig() *ScannerConfig {
+	return &ScannerConfig{
+		RiskThreshold: getEnvInt("RISK_THRESHOLD", scanner.DefaultThreshold),
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+type WorkerConfig struct {
+	Consumer        string
+	BatchSize       int
+	Block           time.Duration
+	ClaimInterval   time.Duration
+	ClaimMinIdle    time.Duration
+	MaxDeliveries   int
+	RetryBackoff    time.Duration
+	BacklogInterval time.Duration
+	MetricsAddr     string
+}
+
+func NewWorkerConfig() *WorkerConfig {
+	hostname, _ := os.Hostname()
+
+	return &WorkerConfig{
+		Consumer:        getEnv("WORKER_CONSUMER", hostname),
+		BatchSize:       getEnvInt("WORKER_BATCH_SIZE", 500),
+		Block:           getEnvDuration("WORKER_BLOCK", 2*time.Second),
+		ClaimInterval:   getEnvDuration("WORKER_CLAIM_INTERVAL", 30*time.Second),
+		ClaimMinIdle:    getEnvDuration("WORKER_CLAIM_MIN_IDLE", time.Minute),
+		MaxDeliveries:   getEnvInt("WORKER_MAX_DELIVERIES", 5),
+		RetryBackoff:    getEnvDuration("WORKER_RETRY_BACKOFF", 5*time.Second),
+		BacklogInterval: getEnvDuration("WORKER_BACKLOG_INTERVAL", 15*time.Second),
+		MetricsAddr:     getEnv("WORKER_METRICS_ADDR", ":9091"),
+	}
+}
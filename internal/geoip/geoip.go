@@ -0,0 +1,59 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is the part of a GeoIP record stored with clicks. Fields are
+// empty when the database has no data for the address.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, e.g. "DE".
+	Country string
+	// Region is the ISO 3166-2 code of the largest subdivision, e.g. "DE-BE".
+	Region string
+	City   string
+}
+
+// Locator resolves addresses against a local MaxMind-format database
+// (GeoLite2/GeoIP2 City or Country). Lookups never leave the process.
+type Locator struct {
+	db *geoip2.Reader
+}
+
+func Open(path string) (*Locator, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: open %s: %w", path, err)
+	}
+
+	return &Locator{db: db}, nil
+}
+
+func (l *Locator) Lookup(rawIP string) Location {
+	ip := net.ParseIP(rawIP)
+	if ip == nil {
+		return Location{}
+	}
+
+	record, err := l.db.City(ip)
+	if err != nil {
+		return Location{}
+	}
+
+	loc := Location{
+		Country: record.Country.IsoCode,
+		City:    record.City.Names["en"],
+	}
+	if len(record.Subdivisions) > 0 && loc.Country != "" && record.Subdivisions[0].IsoCode != "" {
+		loc.Region = loc.Country + "-" + record.Subdivisions[0].IsoCode
+	}
+
+	return loc
+}
+
+func (l *Locator) Close() error {
+	return l.db.Close()
+}
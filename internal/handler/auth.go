@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireToken protects routes with a static bearer token sent in the
+// Authorization header. An empty token disables the routes entirely.
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return requireToken(token, false)
+}
+
+// RequireStreamToken is RequireToken for event streams. EventSource cannot
+// set headers, so the token is also accepted in the "token" query parameter.
+// Use it only where needed: query strings end up in access logs.
+func RequireStreamToken(token string) func(http.Handler) http.Handler {
+	return requireToken(token, true)
+}
+
+func requireToken(token string, allowQuery bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.Error(w, "Admin token is not configured", http.StatusForbidden)
+				return
+			}
+
+			var given string
+			if allowQuery {
+				given = r.URL.Query().Get("token")
+			}
+			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				given = strings.TrimPrefix(auth, "Bearer ")
+			}
+
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
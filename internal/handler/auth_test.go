@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		middleware func(string) func(http.Handler) http.Handler
+		target     string
+		header     string
+		want       int
+	}{
+		{name: "header", middleware: RequireToken, target: "/", header: "Bearer secret", want: http.StatusOK},
+		{name: "wrong header", middleware: RequireToken, target: "/", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "query ignored", middleware: RequireToken, target: "/?token=secret", want: http.StatusUnauthorized},
+		{name: "stream header", middleware: RequireStreamToken, target: "/", header: "Bearer secret", want: http.StatusOK},
+		{name: "stream query", middleware: RequireStreamToken, target: "/?token=secret", want: http.StatusOK},
+		{name: "stream wrong query", middleware: RequireStreamToken, target: "/?token=wrong", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.middleware("secret")(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	RequireToken("")(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status without a configured token = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
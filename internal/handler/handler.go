@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -8,16 +9,31 @@ import (
 	"time"
 
 	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/realip"
 	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/resolver"
 	"github.com/Vadim-Makhnev/url-shortener/internal/service"
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 type URLService interface {
 	ShortenURL(originalURL string) (*service.URL, error)
-	GetOriginalURL(shortCode string) (string, error)
+	GetOriginalURL(shortCode string) (*service.URL, error)
 	GetAllURLS() ([]service.URL, error)
+	GetBrokenURLs() ([]service.URL, error)
+	DeleteURL(shortCode string) error
+	StartCacheWarmup(ctx context.Context) error
+}
+
+type AnalyticsService interface {
+	TrackClick(click service.Click)
+	GetStats(q service.StatsQuery) (*service.Stats, error)
+	GetTopLinks(window string, limit int) ([]service.LinkRank, error)
+	GetTrendingLinks(window string, limit int) ([]service.LinkRank, error)
+}
+
+type ClientIPResolver interface {
+	ClientIP(r *http.Request) string
 }
 
 type ShortenRequest struct {
@@ -25,23 +41,49 @@ type ShortenRequest struct {
 }
 
 type URLResponse struct {
-	ShortURL    string    `json:"short_url"`
-	OriginalURL string    `json:"original_url"`
-	CreatedAt   time.Time `json:"created_at"`
+	ShortURL      string    `json:"short_url"`
+	OriginalURL   string    `json:"original_url"`
+	FinalURL      string    `json:"final_url,omitempty"`
+	RedirectChain []string  `json:"redirect_chain,omitempty"`
+	RiskScore     int       `json:"risk_score"`
+	Flagged       bool      `json:"flagged"`
+	ClickCount    int64     `json:"click_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type BrokenLinkResponse struct {
+	ShortURL      string    `json:"short_url"`
+	OriginalURL   string    `json:"original_url"`
+	LastStatus    int       `json:"last_status,omitempty"`
+	LastCheckedAt time.Time `json:"last_checked_at"`
+	CheckFailures int       `json:"check_failures"`
+	Disabled      bool      `json:"disabled"`
 }
 
 type URLHandler struct {
-	service URLService
+	service   URLService
+	analytics AnalyticsService
+	clientIPs ClientIPResolver
+	live      LiveFeed
+}
+
+func NewHandler(service URLService, analytics AnalyticsService) *URLHandler {
+	return &URLHandler{
+		service:   service,
+		analytics: analytics,
+		clientIPs: &realip.Resolver{},
+	}
 }
 
-func NewHandler(service URLService) *URLHandler {
-	return &URLHandler{service: service}
+// WithClientIPResolver replaces the default resolver, which trusts no
+// proxies and always uses the peer address.
+func (h *URLHandler) WithClientIPResolver(clientIPs ClientIPResolver) *URLHandler {
+	h.clientIPs = clientIPs
+	return h
 }
 
 func (h *URLHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	metrics.URLShortenRequests.Inc()
-	timer := prometheus.NewTimer(metrics.RequestDuration)
-	defer timer.ObserveDuration()
 
 	var req ShortenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -56,15 +98,19 @@ func (h *URLHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 	url, err := h.service.ShortenURL(req.URL)
 	if err != nil {
+		if errors.Is(err, resolver.ErrRedirectLoop) {
+			http.Error(w, "URL redirects in a loop", http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, resolver.ErrSelfRedirect) {
+			http.Error(w, "URL redirects back to this service", http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, "failed to shorten URL", http.StatusInternalServerError)
 		return
 	}
 
-	var res URLResponse
-
-	res.ShortURL = os.Getenv("BASE_URL") + "/" + url.ShortCode
-	res.CreatedAt = url.CreatedAt
-	res.OriginalURL = url.OriginalURL
+	res := newURLResponse(url)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -73,13 +119,11 @@ func (h *URLHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 	metrics.URLRedirectRequests.Inc()
-	timer := prometheus.NewTimer(metrics.RequestDuration)
-	defer timer.ObserveDuration()
 
 	vars := mux.Vars(r)
 	shortCode := vars["shortCode"]
 
-	originalURL, err := h.service.GetOriginalURL(shortCode)
+	url, err := h.service.GetOriginalURL(shortCode)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			http.Error(w, "URL not found", http.StatusNotFound)
@@ -89,13 +133,63 @@ func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metrics.URLAccessCount.WithLabelValues(shortCode).Inc()
+	if url.Disabled {
+		http.Error(w, "URL is disabled", http.StatusGone)
+		return
+	}
 
-	redirectURL := originalURL
+	if url.Flagged && r.URL.Query().Get(confirmParam) != "1" {
+		metrics.URLInterstitialViews.Inc()
+		renderInterstitial(w, url)
+		return
+	}
+
+	h.analytics.TrackClick(service.Click{
+		ShortCode: shortCode,
+		ClickedAt: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        h.clientIPs.ClientIP(r),
+	})
+
+	redirectURL := url.OriginalURL
 
 	http.Redirect(w, r, redirectURL, http.StatusFound)
 }
 
+func (h *URLHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	if err := h.service.DeleteURL(shortCode); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete URL", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *URLHandler) WarmCache(w http.ResponseWriter, r *http.Request) {
+	// The warm-up outlives the request.
+	if err := h.service.StartCacheWarmup(context.WithoutCancel(r.Context())); err != nil {
+		if errors.Is(err, service.ErrWarmupRunning) {
+			http.Error(w, "Cache warm-up is already running", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, service.ErrWarmupDisabled) {
+			http.Error(w, "Cache warm-up is disabled", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to start cache warm-up", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func (h *URLHandler) GetURLs(w http.ResponseWriter, r *http.Request) {
 	list, err := h.service.GetAllURLS()
 	if err != nil {
@@ -106,13 +200,46 @@ func (h *URLHandler) GetURLs(w http.ResponseWriter, r *http.Request) {
 	var urls []URLResponse
 
 	for _, url := range list {
-		urls = append(urls, URLResponse{
-			ShortURL:    os.Getenv("BASE_URL") + "/" + url.ShortCode,
-			OriginalURL: url.OriginalURL,
-			CreatedAt:   url.CreatedAt,
-		})
+		urls = append(urls, newURLResponse(&url))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(urls)
 }
+
+func (h *URLHandler) BrokenLinks(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.GetBrokenURLs()
+	if err != nil {
+		http.Error(w, "Failed to get broken links", http.StatusInternalServerError)
+		return
+	}
+
+	links := []BrokenLinkResponse{}
+
+	for _, url := range list {
+		links = append(links, BrokenLinkResponse{
+			ShortURL:      os.Getenv("BASE_URL") + "/" + url.ShortCode,
+			OriginalURL:   url.OriginalURL,
+			LastStatus:    url.LastStatus,
+			LastCheckedAt: url.LastCheckedAt,
+			CheckFailures: url.CheckFailures,
+			Disabled:      url.Disabled,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+func newURLResponse(url *service.URL) URLResponse {
+	return URLResponse{
+		ShortURL:      os.Getenv("BASE_URL") + "/" + url.ShortCode,
+		OriginalURL:   url.OriginalURL,
+		FinalURL:      url.FinalURL,
+		RedirectChain: url.RedirectChain,
+		RiskScore:     url.RiskScore,
+		Flagged:       url.Flagged,
+		ClickCount:    url.ClickCount,
+		CreatedAt:     url.CreatedAt,
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/service"
+)
+
+const confirmParam = "confirm"
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Suspicious link</title>
+<style>
+body { font-family: sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+h1 { color: #b00020; }
+code { word-break: break-all; background: #f4f4f4; padding: 0.2rem; }
+button { margin-top: 1rem; padding: 0.5rem 1rem; }
+</style>
+</head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The destination of this short link looks suspicious and may try to steal passwords or other personal information.</p>
+<p>Destination: <code>{{.OriginalURL}}</code></p>
+<form method="get" action="/{{.ShortCode}}">
+<input type="hidden" name="` + confirmParam + `" value="1">
+<button type="submit">Continue anyway</button>
+</form>
+</body>
+</html>
+`))
+
+func renderInterstitial(w http.ResponseWriter, url *service.URL) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	interstitialTemplate.Execute(w, url)
+}
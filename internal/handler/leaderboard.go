@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/service"
+)
+
+const defaultLeaderboardLimit = 10
+
+type LeaderboardResponse struct {
+	Window string             `json:"window"`
+	Links  []LinkRankResponse `json:"links"`
+}
+
+type LinkRankResponse struct {
+	ShortURL       string `json:"short_url"`
+	OriginalURL    string `json:"original_url"`
+	Clicks         int64  `json:"clicks"`
+	PreviousClicks int64  `json:"previous_clicks,omitempty"`
+	Change         int64  `json:"change,omitempty"`
+}
+
+func (h *URLHandler) TopLinks(w http.ResponseWriter, r *http.Request) {
+	h.leaderboard(w, r, h.analytics.GetTopLinks)
+}
+
+func (h *URLHandler) TrendingLinks(w http.ResponseWriter, r *http.Request) {
+	h.leaderboard(w, r, h.analytics.GetTrendingLinks)
+}
+
+func (h *URLHandler) leaderboard(w http.ResponseWriter, r *http.Request, get func(window string, limit int) ([]service.LinkRank, error)) {
+	query := r.URL.Query()
+
+	window := query.Get("window")
+	if window == "" {
+		window = "day"
+	}
+
+	limit := defaultLeaderboardLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid 'limit', expected a number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	links, err := get(window, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRange):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrLeaderboardDisabled):
+			http.Error(w, "Leaderboard is disabled", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to get links", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	res := LeaderboardResponse{
+		Window: window,
+		Links:  []LinkRankResponse{},
+	}
+	for _, link := range links {
+		res.Links = append(res.Links, LinkRankResponse{
+			ShortURL:       os.Getenv("BASE_URL") + "/" + link.ShortCode,
+			OriginalURL:    link.OriginalURL,
+			Clicks:         link.Clicks,
+			PreviousClicks: link.PreviousClicks,
+			Change:         link.Change,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
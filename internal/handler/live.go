@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/live"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+const liveHeartbeatInterval = 15 * time.Second
+
+type LiveFeed interface {
+	Subscribe(shortCode string) *live.Subscriber
+	Unsubscribe(sub *live.Subscriber)
+}
+
+type LiveClickResponse struct {
+	ShortCode      string    `json:"short_code"`
+	ClickedAt      time.Time `json:"clicked_at"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	DeviceType     string    `json:"device_type,omitempty"`
+	IsBot          bool      `json:"is_bot"`
+	Country        string    `json:"country,omitempty"`
+	City           string    `json:"city,omitempty"`
+}
+
+// WithLiveFeed enables the live click stream endpoints.
+func (h *URLHandler) WithLiveFeed(feed LiveFeed) *URLHandler {
+	h.live = feed
+	return h
+}
+
+// LiveClicks streams click events as Server-Sent Events: for every link, or
+// for one link when the route has a short code.
+func (h *URLHandler) LiveClicks(w http.ResponseWriter, r *http.Request) {
+	if h.live == nil {
+		http.Error(w, "Live click stream is disabled", http.StatusNotFound)
+		return
+	}
+
+	shortCode := mux.Vars(r)["shortCode"]
+	if shortCode != "" {
+		if _, err := h.service.GetOriginalURL(shortCode); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				http.Error(w, "URL not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sub := h.live.Subscribe(shortCode)
+	if sub == nil {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.live.Unsubscribe(sub)
+
+	// The stream outlives the server write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case click, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if dropped := sub.Dropped(); dropped > 0 {
+				err = writeEvent(w, "dropped", map[string]int64{"count": dropped})
+			}
+			if err == nil {
+				err = writeEvent(w, "click", newLiveClickResponse(click))
+			}
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+func newLiveClickResponse(click *repository.Click) LiveClickResponse {
+	return LiveClickResponse{
+		ShortCode:      click.ShortCode,
+		ClickedAt:      click.ClickedAt,
+		ReferrerDomain: click.ReferrerDomain,
+		Browser:        click.Browser,
+		OS:             click.OS,
+		DeviceType:     click.DeviceType,
+		IsBot:          click.IsBot,
+		Country:        click.Country,
+		City:           click.City,
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+// InstrumentRoutes is a mux middleware recording request metrics per route
+// template, e.g. "/{shortCode}", so labels stay bounded regardless of the
+// paths requested. mux only runs middleware on matched routes, so the
+// router's NotFoundHandler and MethodNotAllowedHandler must be wrapped too;
+// their requests are recorded as "unmatched".
+func InstrumentRoutes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		method := methodLabel(r.Method)
+
+		inFlight := metrics.HTTPRequestsInFlight.WithLabelValues(route, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(rec, r)
+
+		code := strconv.Itoa(rec.status)
+		metrics.HTTPRequests.WithLabelValues(route, method, code).Inc()
+		metrics.RequestDuration.WithLabelValues(route, method, code).Observe(time.Since(start).Seconds())
+		metrics.HTTPResponseSize.WithLabelValues(route, method, code).Observe(float64(rec.bytes))
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// MethodNotAllowed replies like the mux default for requests whose path
+// matches a route but whose method does not.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// methodLabel keeps the method label bounded: unmatched requests can carry
+// any method a client makes up.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streaming responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
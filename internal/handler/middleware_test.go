@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newInstrumentedRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.Use(InstrumentRoutes)
+	r.NotFoundHandler = InstrumentRoutes(http.NotFoundHandler())
+	r.MethodNotAllowedHandler = InstrumentRoutes(http.HandlerFunc(MethodNotAllowed))
+
+	r.HandleFunc("/api/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}).Methods("GET")
+
+	return r
+}
+
+func TestInstrumentRoutes(t *testing.T) {
+	router := newInstrumentedRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		label  string
+		status int
+	}{
+		{name: "matched", method: "GET", path: "/api/items/42", route: "/api/items/{id}", label: "GET", status: http.StatusOK},
+		{name: "not found", method: "GET", path: "/nope/42", route: "unmatched", label: "GET", status: http.StatusNotFound},
+		{name: "method not allowed", method: "DELETE", path: "/api/items/42", route: "unmatched", label: "DELETE", status: http.StatusMethodNotAllowed},
+		{name: "unknown method", method: "BREW", path: "/nope", route: "unmatched", label: "OTHER", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.HTTPRequests.WithLabelValues(tt.route, tt.label, strconv.Itoa(tt.status))
+			before := testutil.ToFloat64(counter)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests{route=%q, method=%q} grew by %v, want 1", tt.route, tt.label, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/service"
+	"github.com/gorilla/mux"
+)
+
+const defaultStatsRange = 7 * 24 * time.Hour
+
+type StatsResponse struct {
+	ShortCode        string           `json:"short_code"`
+	From             time.Time        `json:"from"`
+	To               time.Time        `json:"to"`
+	Bucket           string           `json:"bucket"`
+	IncludeBots      bool             `json:"include_bots"`
+	Total            int64            `json:"total"`
+	Series           []BucketResponse `json:"series"`
+	Referrers        []CountResponse  `json:"referrers"`
+	Browsers         []CountResponse  `json:"browsers"`
+	OperatingSystems []CountResponse  `json:"operating_systems"`
+	Devices          []CountResponse  `json:"devices"`
+	Countries        []CountResponse  `json:"countries"`
+	Regions          []CountResponse  `json:"regions"`
+	Cities           []CountResponse  `json:"cities"`
+
+	UniqueVisitors      int64            `json:"unique_visitors"`
+	DailyUniqueVisitors []BucketResponse `json:"daily_unique_visitors"`
+}
+
+type BucketResponse struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type CountResponse struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+func (h *URLHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+	query := r.URL.Query()
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid 'to' timestamp, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultStatsRange)
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid 'from' timestamp, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+
+	bucket := query.Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+
+	includeBots := false
+	if v := query.Get("include_bots"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid 'include_bots', expected true or false", http.StatusBadRequest)
+			return
+		}
+		includeBots = b
+	}
+
+	stats, err := h.analytics.GetStats(service.StatsQuery{
+		ShortCode:   shortCode,
+		From:        from,
+		To:          to,
+		Bucket:      bucket,
+		IncludeBots: includeBots,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRange):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrNotFound):
+			http.Error(w, "URL not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	res := StatsResponse{
+		ShortCode:        stats.ShortCode,
+		From:             stats.From,
+		To:               stats.To,
+		Bucket:           stats.Bucket,
+		IncludeBots:      stats.IncludeBots,
+		Total:            stats.Total,
+		Series:           newBucketResponses(stats.Series),
+		Referrers:        newCountResponses(stats.Referrers),
+		Browsers:         newCountResponses(stats.Browsers),
+		OperatingSystems: newCountResponses(stats.OperatingSystems),
+		Devices:          newCountResponses(stats.Devices),
+		Countries:        newCountResponses(stats.Countries),
+		Regions:          newCountResponses(stats.Regions),
+		Cities:           newCountResponses(stats.Cities),
+
+		UniqueVisitors:      stats.UniqueVisitors,
+		DailyUniqueVisitors: newBucketResponses(stats.DailyUniqueVisitors),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func newBucketResponses(buckets []service.StatsBucket) []BucketResponse {
+	res := []BucketResponse{}
+	for _, b := range buckets {
+		res = append(res, BucketResponse{Start: b.Start, Count: b.Count})
+	}
+	return res
+}
+
+func newCountResponses(counts []service.StatsCount) []CountResponse {
+	res := []CountResponse{}
+	for _, c := range counts {
+		res = append(res, CountResponse{Value: c.Value, Count: c.Count})
+	}
+	return res
+}
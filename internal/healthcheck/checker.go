@@ -0,0 +1,280 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+const maxBodyBytes = 64 << 10
+
+var (
+	ErrPrivateAddress   = errors.New("destination resolves to a private address")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+type Repository interface {
+	GetURLsDueForCheck(checkedBefore time.Time, limit int) ([]repository.URL, error)
+	UpdateCheckResult(id int, result repository.CheckResult) (bool, error)
+	CountBrokenURLs() (broken int, disabled int, err error)
+}
+
+type Cache interface {
+	Delete(ctx context.Context, shortCode string) error
+	SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error
+}
+
+type Options struct {
+	Interval     time.Duration
+	RecheckAfter time.Duration
+	BatchSize    int
+	Concurrency  int
+	HostDelay    time.Duration
+	Timeout      time.Duration
+	DisableAfter int
+	// MaxRedirects is the number of redirects followed before a check fails.
+	MaxRedirects int
+	// AllowPrivate lets checks reach loopback, private and link-local
+	// addresses. Destinations are user supplied, so it is meant for tests only.
+	AllowPrivate bool
+}
+
+type Checker struct {
+	repo   Repository
+	cache  Cache
+	opts   Options
+	client *http.Client
+	logger *slog.Logger
+}
+
+func New(repo Repository, cache Cache, opts Options, logger *slog.Logger) *Checker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	return &Checker{
+		repo:   repo,
+		cache:  cache,
+		opts:   opts,
+		client: newClient(opts),
+		logger: logger,
+	}
+}
+
+// newClient builds the HTTP client for checks. Like the resolver, it refuses
+// to connect to private addresses, including after a redirect or a DNS
+// answer that points inside the network.
+func newClient(opts Options) *http.Client {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = rejectPrivate
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          opts.Concurrency,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return nil
+		},
+	}
+}
+
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.RunOnce(ctx); err != nil {
+			c.logger.Error("healthcheck: run", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks one batch of links that are due. Links are grouped by host:
+// hosts are checked concurrently, links on the same host one after another
+// with HostDelay between requests.
+func (c *Checker) RunOnce(ctx context.Context) error {
+	urls, err := c.repo.GetURLsDueForCheck(time.Now().Add(-c.opts.RecheckAfter), c.opts.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	byHost := make(map[string][]repository.URL)
+	for _, u := range urls {
+		host := hostOf(u.OriginalURL)
+		byHost[host] = append(byHost[host], u)
+	}
+
+	sem := make(chan struct{}, c.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for _, group := range byHost {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(group []repository.URL) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				c.checkHost(ctx, group)
+			}(group)
+		}
+	}
+	wg.Wait()
+
+	broken, disabled, err := c.repo.CountBrokenURLs()
+	if err != nil {
+		return err
+	}
+	metrics.BrokenLinks.Set(float64(broken))
+	metrics.DisabledLinks.Set(float64(disabled))
+
+	return ctx.Err()
+}
+
+func (c *Checker) checkHost(ctx context.Context, group []repository.URL) {
+	for i, u := range group {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.opts.HostDelay):
+			}
+		}
+
+		status, err := c.check(ctx, u.OriginalURL)
+		if ctx.Err() != nil {
+			return
+		}
+
+		broken := isBroken(status, err)
+		switch {
+		case err != nil:
+			metrics.LinkChecks.WithLabelValues("error").Inc()
+		case broken:
+			metrics.LinkChecks.WithLabelValues("broken").Inc()
+		default:
+			metrics.LinkChecks.WithLabelValues("ok").Inc()
+		}
+
+		disabled, updateErr := c.repo.UpdateCheckResult(u.ID, repository.CheckResult{
+			Status:       status,
+			Broken:       broken,
+			CheckedAt:    time.Now(),
+			DisableAfter: c.opts.DisableAfter,
+		})
+		if updateErr != nil {
+			c.logger.Error("healthcheck: update", "short_code", u.ShortCode, "error", updateErr)
+			continue
+		}
+
+		if disabled {
+			c.logger.Warn("healthcheck: link disabled", "short_code", u.ShortCode, "original_url", u.OriginalURL, "status", status, "error", err)
+			c.evict(ctx, u.ShortCode)
+		}
+	}
+}
+
+// evict stops the cache from serving a disabled link. Like a delete, it
+// leaves a missing entry behind, so a lookup that read the link before it
+// was disabled cannot fill the cache with it afterwards.
+func (c *Checker) evict(ctx context.Context, shortCode string) {
+	if err := c.cache.Delete(ctx, shortCode); err != nil {
+		c.logger.Error("healthcheck: evict", "short_code", shortCode, "error", err)
+	}
+	if err := c.cache.SetMissing(ctx, shortCode, repository.DeletedCacheTTL); err != nil {
+		c.logger.Error("healthcheck: cache missing code", "short_code", shortCode, "error", err)
+	}
+}
+
+// check issues a HEAD request and falls back to GET for servers that do not
+// support HEAD. It returns the final status code after redirects.
+func (c *Checker) check(ctx context.Context, rawURL string) (int, error) {
+	status, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+		return status, nil
+	}
+
+	return c.do(ctx, http.MethodGet, rawURL)
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "url-shortener-healthcheck/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+
+	return resp.StatusCode, nil
+}
+
+// isBroken treats unreachable hosts, server errors and "not found" responses
+// as broken. Other 4xx responses usually mean the page exists behind a login
+// or rate limit, so they count as healthy.
+func isBroken(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+	return status >= 500 || status == http.StatusNotFound || status == http.StatusGone
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s: %w", address, ErrPrivateAddress)
+	}
+
+	return nil
+}
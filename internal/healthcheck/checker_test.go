@@ -0,0 +1,247 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type fakeRepository struct {
+	mu       sync.Mutex
+	urls     []repository.URL
+	failures map[int]int
+	disabled map[int]bool
+}
+
+func newFakeRepository(urls ...repository.URL) *fakeRepository {
+	return &fakeRepository{
+		urls:     urls,
+		failures: make(map[int]int),
+		disabled: make(map[int]bool),
+	}
+}
+
+func (r *fakeRepository) GetURLsDueForCheck(checkedBefore time.Time, limit int) ([]repository.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []repository.URL
+	for _, u := range r.urls {
+		if !r.disabled[u.ID] {
+			due = append(due, u)
+		}
+	}
+	return due, nil
+}
+
+// UpdateCheckResult mirrors the failure counting of the postgres query.
+func (r *fakeRepository) UpdateCheckResult(id int, result repository.CheckResult) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !result.Broken {
+		r.failures[id] = 0
+		return r.disabled[id], nil
+	}
+
+	r.failures[id]++
+	if result.DisableAfter > 0 && r.failures[id] >= result.DisableAfter {
+		r.disabled[id] = true
+	}
+	return r.disabled[id], nil
+}
+
+func (r *fakeRepository) CountBrokenURLs() (int, int, error) {
+	return 0, 0, nil
+}
+
+type fakeCache struct {
+	mu      sync.Mutex
+	deleted []string
+	missing map[string]time.Duration
+}
+
+func (c *fakeCache) Delete(ctx context.Context, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted = append(c.deleted, shortCode)
+	return nil
+}
+
+func (c *fakeCache) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.missing == nil {
+		c.missing = make(map[string]time.Duration)
+	}
+	c.missing[shortCode] = ttl
+	return nil
+}
+
+func newTestChecker(repo Repository, cache Cache, opts Options) *Checker {
+	opts.Timeout = 2 * time.Second
+	opts.AllowPrivate = true
+	if opts.MaxRedirects == 0 {
+		opts.MaxRedirects = 5
+	}
+	return New(repo, cache, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestIsBroken(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{status: http.StatusOK, want: false},
+		{status: http.StatusUnauthorized, want: false},
+		{status: http.StatusTooManyRequests, want: false},
+		{status: http.StatusNotFound, want: true},
+		{status: http.StatusGone, want: true},
+		{status: http.StatusBadGateway, want: true},
+		{err: errors.New("connection refused"), want: true},
+	}
+
+	for _, tt := range tests {
+		if got := isBroken(tt.status, tt.err); got != tt.want {
+			t.Errorf("isBroken(%d, %v) = %v, want %v", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestChecker_ChecksHostSequentially(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// 127.0.0.1 and localhost are different hosts to the checker, so the two
+	// groups may run concurrently while each group runs one request at a time.
+	other := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	repo := newFakeRepository(
+		repository.URL{ID: 1, ShortCode: "a", OriginalURL: srv.URL + "/a"},
+		repository.URL{ID: 2, ShortCode: "b", OriginalURL: srv.URL + "/b"},
+		repository.URL{ID: 3, ShortCode: "c", OriginalURL: srv.URL + "/c"},
+		repository.URL{ID: 4, ShortCode: "d", OriginalURL: other + "/d"},
+	)
+
+	checker := newTestChecker(repo, &fakeCache{}, Options{Concurrency: 4, BatchSize: 10})
+	if err := checker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("max concurrent requests = %d, want at most one per host", got)
+	}
+	for id := 1; id <= 4; id++ {
+		if repo.failures[id] != 0 {
+			t.Errorf("link %d counted as failing", id)
+		}
+	}
+}
+
+func TestChecker_HostDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	repo := newFakeRepository(
+		repository.URL{ID: 1, ShortCode: "a", OriginalURL: srv.URL + "/a"},
+		repository.URL{ID: 2, ShortCode: "b", OriginalURL: srv.URL + "/b"},
+		repository.URL{ID: 3, ShortCode: "c", OriginalURL: srv.URL + "/c"},
+	)
+
+	checker := newTestChecker(repo, &fakeCache{}, Options{Concurrency: 4, BatchSize: 10, HostDelay: 50 * time.Millisecond})
+
+	start := time.Now()
+	if err := checker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("three links on one host took %v, want at least two host delays", elapsed)
+	}
+}
+
+func TestChecker_DisablesAfterThreshold(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := newFakeRepository(
+		repository.URL{ID: 1, ShortCode: "gone", OriginalURL: srv.URL + "/gone"},
+		repository.URL{ID: 2, ShortCode: "ok", OriginalURL: srv.URL + "/ok"},
+	)
+	cache := &fakeCache{}
+	checker := newTestChecker(repo, cache, Options{Concurrency: 1, BatchSize: 10, DisableAfter: 3})
+
+	for run := 1; run <= 3; run++ {
+		if err := checker.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if run < 3 && len(cache.deleted) != 0 {
+			t.Fatalf("run %d: link evicted before reaching the threshold", run)
+		}
+	}
+
+	if !repo.disabled[1] {
+		t.Error("broken link not disabled after 3 failed checks")
+	}
+	if repo.disabled[2] {
+		t.Error("healthy link disabled")
+	}
+	if len(cache.deleted) != 1 || cache.deleted[0] != "gone" {
+		t.Errorf("evicted %v, want [gone]", cache.deleted)
+	}
+	// A lookup that read the link before it was disabled must not be able
+	// to cache it again.
+	if len(cache.missing) != 1 || cache.missing["gone"] != repository.DeletedCacheTTL {
+		t.Errorf("missing entries = %v, want gone for %v", cache.missing, repository.DeletedCacheTTL)
+	}
+}
+
+func TestChecker_RejectsPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	checker := New(newFakeRepository(), &fakeCache{}, Options{Timeout: 2 * time.Second, MaxRedirects: 5}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := checker.check(context.Background(), srv.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected ErrPrivateAddress, got %v", err)
+	}
+}
+
+func TestChecker_RedirectLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	checker := newTestChecker(newFakeRepository(), &fakeCache{}, Options{MaxRedirects: 3})
+
+	_, err := checker.check(context.Background(), srv.URL+"/")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("expected ErrTooManyRedirects, got %v", err)
+	}
+}
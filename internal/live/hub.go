@@ -0,0 +1,129 @@
+package live
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+const feedRetry = 5 * time.Second
+
+type Feed interface {
+	Subscribe(ctx context.Context, handle func(*repository.Click)) error
+}
+
+// Subscriber receives clicks for one link, or for all links when ShortCode
+// is empty. Events are delivered through a bounded buffer; when a client
+// cannot keep up, new events are dropped and counted instead of blocking
+// the hub or the other subscribers.
+type Subscriber struct {
+	ShortCode string
+	C         <-chan *repository.Click
+
+	events  chan *repository.Click
+	dropped atomic.Int64
+}
+
+// Dropped returns the number of events dropped since the last call.
+func (s *Subscriber) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Hub holds a single feed subscription per instance and fans the events out
+// to the local SSE clients.
+type Hub struct {
+	mu         sync.Mutex
+	subs       map[*Subscriber]struct{}
+	closed     bool
+	bufferSize int
+	retry      time.Duration
+	logger     *slog.Logger
+}
+
+func NewHub(bufferSize int, logger *slog.Logger) *Hub {
+	return &Hub{
+		subs:       make(map[*Subscriber]struct{}),
+		bufferSize: bufferSize,
+		retry:      feedRetry,
+		logger:     logger,
+	}
+}
+
+// Run relays the feed until ctx is done, then closes every subscriber so
+// open streams end and the server can shut down. A failed or dropped
+// subscription is retried; clicks published meanwhile are lost.
+func (h *Hub) Run(ctx context.Context, feed Feed) {
+	for ctx.Err() == nil {
+		if err := feed.Subscribe(ctx, h.broadcast); err != nil {
+			h.logger.Error("live: subscribe", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(h.retry):
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		close(sub.events)
+		delete(h.subs, sub)
+	}
+	metrics.LiveSubscribers.Set(0)
+}
+
+// Subscribe registers a subscriber. It returns nil once the hub has stopped.
+func (h *Hub) Subscribe(shortCode string) *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	events := make(chan *repository.Click, h.bufferSize)
+	sub := &Subscriber{ShortCode: shortCode, C: events, events: events}
+	h.subs[sub] = struct{}{}
+	metrics.LiveSubscribers.Inc()
+
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+
+	delete(h.subs, sub)
+	close(sub.events)
+	metrics.LiveSubscribers.Dec()
+}
+
+func (h *Hub) broadcast(click *repository.Click) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if sub.ShortCode != "" && sub.ShortCode != click.ShortCode {
+			continue
+		}
+
+		select {
+		case sub.events <- click:
+		default:
+			sub.dropped.Add(1)
+			metrics.LiveEventsDropped.Inc()
+		}
+	}
+}
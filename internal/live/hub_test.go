@@ -0,0 +1,98 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+// fakeFeed ends the first subscriptions with errs, a nil entry standing for
+// a dropped connection, then delivers clicks and stops the hub.
+type fakeFeed struct {
+	errs   []error
+	clicks []*repository.Click
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (f *fakeFeed) Subscribe(ctx context.Context, handle func(*repository.Click)) error {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+
+	for _, click := range f.clicks {
+		handle(click)
+	}
+	f.cancel()
+	return nil
+}
+
+func TestHub_FanOut(t *testing.T) {
+	hub := NewHub(2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	all := hub.Subscribe("")
+	one := hub.Subscribe("abc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feed := &fakeFeed{cancel: cancel, clicks: []*repository.Click{
+		{ShortCode: "abc"},
+		{ShortCode: "xyz"},
+		{ShortCode: "abc"},
+	}}
+	hub.Run(ctx, feed)
+
+	var got []string
+	for click := range all.C {
+		got = append(got, click.ShortCode)
+	}
+	if dropped := all.Dropped(); len(got) != 2 || dropped != 1 {
+		t.Errorf("global subscriber got %v, dropped %d; want 2 events and 1 dropped", got, dropped)
+	}
+
+	got = nil
+	for click := range one.C {
+		got = append(got, click.ShortCode)
+	}
+	if len(got) != 2 || got[0] != "abc" || got[1] != "abc" {
+		t.Errorf("link subscriber got %v, want [abc abc]", got)
+	}
+
+	if hub.Subscribe("") != nil {
+		t.Error("Subscribe after Run returned a subscriber")
+	}
+}
+
+func TestHub_ResubscribesAfterFailure(t *testing.T) {
+	hub := NewHub(4, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hub.retry = time.Millisecond
+
+	sub := hub.Subscribe("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feed := &fakeFeed{
+		errs:   []error{errors.New("connection refused"), nil},
+		clicks: []*repository.Click{{ShortCode: "abc"}},
+		cancel: cancel,
+	}
+	hub.Run(ctx, feed)
+
+	if feed.calls != 3 {
+		t.Errorf("subscribed %d times, want 3", feed.calls)
+	}
+
+	var got []string
+	for click := range sub.C {
+		got = append(got, click.ShortCode)
+	}
+	if len(got) != 1 || got[0] != "abc" {
+		t.Errorf("subscriber got %v, want the click delivered after resubscribing", got)
+	}
+}
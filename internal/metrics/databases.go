@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// InstrumentPostgres exports the postgres connection pool statistics. Call
+// it once per process.
+func InstrumentPostgres(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// InstrumentRedis times Redis commands and exports the Redis connection pool
+// statistics. Call it once per process.
+func InstrumentRedis(redis RedisClient) {
+	redis.AddHook(redisHook{})
+	prometheus.MustRegister(redisPoolCollector{client: redis})
+}
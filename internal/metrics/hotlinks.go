@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var hotLinkDesc = prometheus.NewDesc(
+	"url_hot_link_clicks",
+	"Decayed click counts of the links currently in the top K, one series per link",
+	[]string{"short_code"}, nil,
+)
+
+// HotLinks tracks the most clicked links with the Space-Saving algorithm in
+// a fixed amount of memory and exports only the top K of them, so at most K
+// series exist at a time no matter how many links exist. Series are keyed by
+// short code alone, so a link moving within the top keeps its series. Counts
+// are halved every half-life, so links that stop being clicked drop out of
+// the top and their series go stale.
+type HotLinks struct {
+	mu       sync.Mutex
+	topK     int
+	capacity int
+	entries  map[string]*hotLink
+	heap     hotLinkHeap
+}
+
+type hotLink struct {
+	shortCode string
+	count     float64
+	index     int
+}
+
+func NewHotLinks(topK, capacity int) *HotLinks {
+	if capacity < topK {
+		capacity = topK
+	}
+
+	return &HotLinks{
+		topK:     topK,
+		capacity: capacity,
+		entries:  make(map[string]*hotLink, capacity),
+	}
+}
+
+func (h *HotLinks) Add(shortCode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e, ok := h.entries[shortCode]; ok {
+		e.count++
+		heap.Fix(&h.heap, e.index)
+		return
+	}
+
+	if len(h.entries) < h.capacity {
+		e := &hotLink{shortCode: shortCode, count: 1}
+		h.entries[shortCode] = e
+		heap.Push(&h.heap, e)
+		return
+	}
+
+	// Replace the least counted link. The newcomer inherits its count, which
+	// over-estimates it by at most that much but never misses a heavy hitter.
+	e := h.heap[0]
+	delete(h.entries, e.shortCode)
+	e.shortCode = shortCode
+	e.count++
+	h.entries[shortCode] = e
+	heap.Fix(&h.heap, 0)
+}
+
+// Decay halves all counts. Uniform scaling keeps the heap order intact.
+func (h *HotLinks) Decay() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range h.heap {
+		e.count /= 2
+	}
+}
+
+// Run decays the counts every halfLife until ctx is done.
+func (h *HotLinks) Run(ctx context.Context, halfLife time.Duration) {
+	ticker := time.NewTicker(halfLife)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Decay()
+		}
+	}
+}
+
+// Top returns up to n links ordered by descending count.
+func (h *HotLinks) Top(n int) []HotLink {
+	h.mu.Lock()
+	top := make([]HotLink, 0, len(h.heap))
+	for _, e := range h.heap {
+		top = append(top, HotLink{ShortCode: e.shortCode, Count: e.count})
+	}
+	h.mu.Unlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].ShortCode < top[j].ShortCode
+	})
+
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+type HotLink struct {
+	ShortCode string
+	Count     float64
+}
+
+func (h *HotLinks) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hotLinkDesc
+}
+
+func (h *HotLinks) Collect(ch chan<- prometheus.Metric) {
+	for _, link := range h.Top(h.topK) {
+		ch <- prometheus.MustNewConstMetric(hotLinkDesc, prometheus.GaugeValue, link.Count, link.ShortCode)
+	}
+}
+
+// hotLinkHeap is a min-heap on count, so the eviction candidate is at the root.
+type hotLinkHeap []*hotLink
+
+func (h hotLinkHeap) Len() int           { return len(h) }
+func (h hotLinkHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotLinkHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotLinkHeap) Push(x any) {
+	e := x.(*hotLink)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hotLinkHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHotLinks_Top(t *testing.T) {
+	h := NewHotLinks(2, 3)
+
+	for i := 0; i < 5; i++ {
+		h.Add("a")
+	}
+	for i := 0; i < 3; i++ {
+		h.Add("b")
+	}
+	h.Add("c")
+	// Evicts "c", the least counted link.
+	h.Add("d")
+
+	top := h.Top(2)
+	if len(top) != 2 || top[0].ShortCode != "a" || top[1].ShortCode != "b" {
+		t.Fatalf("Top(2) = %+v, want a, b", top)
+	}
+
+	all := h.Top(10)
+	if len(all) != 3 {
+		t.Fatalf("Top(10) returned %d links, want capacity 3", len(all))
+	}
+	for _, link := range all {
+		if link.ShortCode == "c" {
+			t.Errorf("evicted link c still tracked")
+		}
+	}
+}
+
+func TestHotLinks_Decay(t *testing.T) {
+	h := NewHotLinks(1, 2)
+
+	for i := 0; i < 4; i++ {
+		h.Add("old")
+	}
+	h.Decay()
+	h.Decay()
+	for i := 0; i < 2; i++ {
+		h.Add("new")
+	}
+
+	if top := h.Top(1); top[0].ShortCode != "new" {
+		t.Errorf("Top(1) = %+v, want new after decay", top)
+	}
+}
+
+func TestHotLinks_CollectExportsTopKByShortCode(t *testing.T) {
+	h := NewHotLinks(2, 3)
+
+	for i := 0; i < 3; i++ {
+		h.Add("a")
+	}
+	for i := 0; i < 2; i++ {
+		h.Add("b")
+	}
+	h.Add("c")
+
+	want := `
+		# HELP url_hot_link_clicks Decayed click counts of the links currently in the top K, one series per link
+		# TYPE url_hot_link_clicks gauge
+		url_hot_link_clicks{short_code="a"} 3
+		url_hot_link_clicks{short_code="b"} 2
+	`
+	if err := testutil.CollectAndCompare(h, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// Overtaking another link changes values, not series.
+	for i := 0; i < 2; i++ {
+		h.Add("b")
+	}
+	want = strings.Replace(want, `{short_code="b"} 2`, `{short_code="b"} 4`, 1)
+	if err := testutil.CollectAndCompare(h, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
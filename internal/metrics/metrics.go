@@ -16,16 +16,205 @@ var (
 		Help: "Total number of URL redirect requests",
 	})
 
-	URLAccessCount = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "url_access_count_total",
-		Help: "Total number of URL accesses",
-	}, []string{"short_code"})
+	URLInterstitialViews = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_interstitial_views_total",
+		Help: "Total number of warning pages shown for flagged URLs",
+	})
 
-	RequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "http_request_duration_seconds",
-		Help:    "Duration of HTTP requests",
+	ClicksDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_clicks_dropped_total",
+		Help: "Total number of clicks dropped because the click counter buffer was unavailable",
+	})
+
+	ClickTrackingDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "click_tracking_dropped_total",
+		Help: "Total number of clicks not tracked because the click tracking queue was full",
+	})
+
+	ClickTrackingQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "click_tracking_queued",
+		Help: "Number of clicks waiting in the click tracking queue",
+	})
+
+	ClickFlushes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_click_flushes_total",
+		Help: "Total number of click count flushes to postgres by result",
+	}, []string{"result"})
+
+	ClickEventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "click_events_published_total",
+		Help: "Total number of click events handed to the click sink by result",
+	}, []string{"result"})
+
+	ClickEventsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "click_events_processed_total",
+		Help: "Total number of click events written to postgres by the click worker",
+	})
+
+	ClickEventsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "click_events_failed_total",
+		Help: "Total number of click events the click worker failed to write and left for redelivery",
+	})
+
+	ClickEventsDeadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "click_events_dead_lettered_total",
+		Help: "Total number of click events moved to the dead-letter stream",
+	})
+
+	ClickStreamLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "click_stream_lag",
+		Help: "Number of click stream entries not yet read by the consumer group",
+	})
+
+	ClickStreamPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "click_stream_pending",
+		Help: "Number of click stream entries read but not yet acknowledged",
+	})
+
+	ClickBatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "click_batch_duration_seconds",
+		Help:    "Duration of click batch inserts performed by the click worker",
 		Buckets: prometheus.DefBuckets,
 	})
+
+	RollupRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "click_rollup_runs_total",
+		Help: "Total number of click rollup runs by result",
+	}, []string{"result"})
+
+	RollupBuckets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "click_rollup_buckets_total",
+		Help: "Total number of link buckets re-aggregated by granularity",
+	}, []string{"granularity"})
+
+	RollupLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "click_rollup_lag_seconds",
+		Help: "Age of the newest click ingestion time covered by the rollups",
+	})
+
+	ClickPartitionRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "click_partition_maintenance_runs_total",
+		Help: "Total number of clicks partition maintenance runs by result",
+	}, []string{"result"})
+
+	LiveSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "live_click_subscribers",
+		Help: "Number of clients connected to the live click stream",
+	})
+
+	LiveEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "live_click_events_dropped_total",
+		Help: "Total number of live click events dropped because a client could not keep up",
+	})
+
+	LinkChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "link_checks_total",
+		Help: "Total number of destination health checks by result",
+	}, []string{"result"})
+
+	BrokenLinks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "links_broken",
+		Help: "Number of enabled links whose last destination check failed",
+	})
+
+	DisabledLinks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "links_disabled",
+		Help: "Number of links disabled after repeated failed destination checks",
+	})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_cache_requests_total",
+		Help: "Total number of short code cache lookups by result (hit, negative_hit, miss, unavailable, error)",
+	}, []string{"result"})
+
+	LocalCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_local_cache_requests_total",
+		Help: "Total number of in-process short code cache lookups by result (hit, miss)",
+	}, []string{"result"})
+
+	LocalCacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_local_cache_invalidations_total",
+		Help: "Total number of in-process cache invalidations received from other instances",
+	})
+
+	CacheBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "url_cache_breaker_state",
+		Help: "State of the cache circuit breaker (0 closed, 1 half-open, 2 open)",
+	})
+
+	CacheBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_cache_breaker_transitions_total",
+		Help: "Total number of cache circuit breaker state changes by new state",
+	}, []string{"state"})
+
+	CacheBreakerRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_cache_breaker_rejected_total",
+		Help: "Total number of cache calls failed fast by the open circuit breaker",
+	})
+
+	CacheWarmupRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_cache_warmup_runs_total",
+		Help: "Total number of cache warm-ups by result",
+	}, []string{"result"})
+
+	CacheWarmupLinks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_cache_warmup_links_total",
+		Help: "Total number of links handled by cache warm-ups by result (loaded, skipped, failed)",
+	}, []string{"result"})
+
+	CacheWarmupProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "url_cache_warmup_progress_ratio",
+		Help: "Share of the links of the current or last cache warm-up that were read from postgres",
+	})
+
+	CacheLookupsCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_cache_lookups_coalesced_total",
+		Help: "Total number of cache misses that shared a postgres lookup with a concurrent miss for the same code",
+	})
+
+	CodeFilterRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "url_code_filter_rejections_total",
+		Help: "Total number of short codes rejected by the code Bloom filter without a postgres lookup",
+	})
+
+	CodeFilterRebuilds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "url_code_filter_rebuilds_total",
+		Help: "Total number of code Bloom filter rebuilds by result",
+	}, []string{"result"})
+
+	RedisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_command_duration_seconds",
+		Help:    "Duration of Redis commands and pipelines by command and result",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "result"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of postgres repository methods by method",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method"})
+
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by route template, method and status code",
+	}, []string{"route", "method", "code"})
+
+	HTTPRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served by route template and method",
+	}, []string{"route", "method"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route template, method and status code",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	HTTPResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "Size of HTTP response bodies by route template, method and status code",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"route", "method", "code"})
 )
 
 func InitMetrics() {}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient is the part of the go-redis clients needed for instrumentation.
+type RedisClient interface {
+	AddHook(hook redis.Hook)
+	PoolStats() *redis.PoolStats
+}
+
+// redisHook times every command, and every pipeline as a whole.
+type redisHook struct{}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		RedisCommandDuration.WithLabelValues(cmd.Name(), redisResult(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		RedisCommandDuration.WithLabelValues("pipeline", redisResult(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// redisResult keeps missing keys apart from failures: redis.Nil is the
+// normal answer to a cache miss.
+func redisResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, redis.Nil):
+		return "nil"
+	default:
+		return "error"
+	}
+}
+
+var (
+	redisPoolHitsDesc = prometheus.NewDesc("redis_pool_hits_total",
+		"Number of times a free connection was found in the pool", nil, nil)
+	redisPoolMissesDesc = prometheus.NewDesc("redis_pool_misses_total",
+		"Number of times a free connection was not found in the pool", nil, nil)
+	redisPoolTimeoutsDesc = prometheus.NewDesc("redis_pool_timeouts_total",
+		"Number of times a wait for a pool connection timed out", nil, nil)
+	redisPoolTotalConnsDesc = prometheus.NewDesc("redis_pool_total_connections",
+		"Number of connections in the pool", nil, nil)
+	redisPoolIdleConnsDesc = prometheus.NewDesc("redis_pool_idle_connections",
+		"Number of idle connections in the pool", nil, nil)
+	redisPoolStaleConnsDesc = prometheus.NewDesc("redis_pool_stale_connections_total",
+		"Number of stale connections removed from the pool", nil, nil)
+)
+
+// redisPoolCollector exports go-redis PoolStats on every scrape.
+type redisPoolCollector struct {
+	client RedisClient
+}
+
+func (c redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisPoolHitsDesc
+	ch <- redisPoolMissesDesc
+	ch <- redisPoolTimeoutsDesc
+	ch <- redisPoolTotalConnsDesc
+	ch <- redisPoolIdleConnsDesc
+	ch <- redisPoolStaleConnsDesc
+}
+
+func (c redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+
+	ch <- prometheus.MustNewConstMetric(redisPoolHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(redisPoolMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(redisPoolTimeoutsDesc, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisPoolTotalConnsDesc, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisPoolIdleConnsDesc, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisPoolStaleConnsDesc, prometheus.CounterValue, float64(stats.StaleConns))
+}
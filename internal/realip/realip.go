@@ -0,0 +1,105 @@
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver finds the client address of a request. X-Forwarded-For is only
+// honoured when the request comes from a trusted proxy, otherwise any client
+// could claim an arbitrary address.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// New parses trusted proxies given as CIDRs or single addresses.
+func New(trusted []string) (*Resolver, error) {
+	r := &Resolver{}
+
+	for _, entry := range trusted {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("realip: invalid trusted proxy %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("realip: invalid trusted proxy %q: %w", entry, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+
+	return r, nil
+}
+
+// ClientIP returns the peer address, or, when the peer is a trusted proxy,
+// the right-most X-Forwarded-For entry that is not itself a trusted proxy.
+// Entries left of that were supplied by the client and cannot be trusted.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer := remoteHost(req.RemoteAddr)
+	if !r.isTrusted(peer) {
+		return peer
+	}
+
+	hops := forwardedFor(req)
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !r.isTrusted(hops[i]) {
+			return hops[i]
+		}
+	}
+
+	// Every hop is a trusted proxy; the left-most one is closest to the client.
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return peer
+}
+
+func (r *Resolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func forwardedFor(req *http.Request) []string {
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, remoteHost(hop))
+			}
+		}
+	}
+	return hops
+}
+
+// remoteHost strips an optional port from addr.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,48 @@
+package realip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	r, err := New([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"untrusted peer ignores header", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted peer without header", "10.1.2.3:1234", "", "10.1.2.3"},
+		{"trusted peer", "10.1.2.3:1234", "198.51.100.1", "198.51.100.1"},
+		{"spoofed left-most entry", "10.1.2.3:1234", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "192.0.2.1:1234", "198.51.100.1, 10.0.0.5", "198.51.100.1"},
+		{"all hops trusted", "10.1.2.3:1234", "10.0.0.9, 10.0.0.5", "10.0.0.9"},
+		{"ipv6 peer", "[2001:db8::1]:1234", "198.51.100.1", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+
+			if got := r.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidProxy(t *testing.T) {
+	if _, err := New([]string{"not-an-ip"}); err == nil {
+		t.Error("expected error for invalid proxy")
+	}
+}
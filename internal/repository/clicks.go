@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	clickColumns = 15
+
+	breakdownLimit = 20
+)
+
+type Click struct {
+	// EventID is the stream message ID the click was delivered with. It makes
+	// redelivered events idempotent.
+	EventID        string    `json:"-"`
+	ShortCode      string    `json:"short_code"`
+	ClickedAt      time.Time `json:"clicked_at"`
+	Referrer       string    `json:"referrer,omitempty"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	Variant        string    `json:"variant,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	DeviceType     string    `json:"device_type,omitempty"`
+	IsBot          bool      `json:"is_bot,omitempty"`
+	Country        string    `json:"country,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	City           string    `json:"city,omitempty"`
+}
+
+type ClickStatsQuery struct {
+	ShortCode string
+	From      time.Time
+	To        time.Time
+	// Bucket is a postgres date_trunc precision: hour, day or week.
+	Bucket      string
+	IncludeBots bool
+}
+
+type ClickBucket struct {
+	Start time.Time
+	Count int64
+}
+
+type DimensionCount struct {
+	Value string
+	Count int64
+}
+
+type ClickStats struct {
+	Total     int64
+	Series    []ClickBucket
+	Referrers []DimensionCount
+	Browsers  []DimensionCount
+	OS        []DimensionCount
+	Devices   []DimensionCount
+	Countries []DimensionCount
+	Regions   []DimensionCount
+	Cities    []DimensionCount
+}
+
+type ClickRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewClickRepository(logger *slog.Logger, postgres *sql.DB) *ClickRepository {
+	return &ClickRepository{
+		db:     postgres,
+		logger: logger,
+	}
+}
+
+// Record writes a single click inline. It satisfies the same interface as
+// ClickStream so the redirect path can write to either.
+func (r *ClickRepository) Record(ctx context.Context, click *Click) error {
+	defer observeQuery("Record", time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := r.insertClicks(ctx, []*Click{click}); err != nil {
+		r.logger.Error("Record", "short_code", click.ShortCode, "error", err)
+		return fmt.Errorf("repository: Record: %w", err)
+	}
+
+	return nil
+}
+
+// InsertClicks writes a batch of clicks in one statement. Clicks whose
+// EventID was already stored are skipped.
+func (r *ClickRepository) InsertClicks(clicks []*Click) error {
+	defer observeQuery("InsertClicks", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.insertClicks(ctx, clicks); err != nil {
+		r.logger.Error("InsertClicks", "clicks", len(clicks), "error", err)
+		return fmt.Errorf("repository: InsertClicks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ClickRepository) insertClicks(ctx context.Context, clicks []*Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	var query strings.Builder
+	query.WriteString(`INSERT INTO clicks (event_id, short_code, clicked_at, referrer, referrer_domain, user_agent, ip, variant,
+		browser, os, device_type, is_bot, country, region, city) VALUES `)
+
+	args := make([]any, 0, len(clicks)*clickColumns)
+	for i, click := range clicks {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * clickColumns
+		query.WriteString("(")
+		for j := 1; j <= clickColumns; j++ {
+			if j > 1 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", n+j)
+		}
+		query.WriteString(")")
+
+		args = append(args, nullString(click.EventID), click.ShortCode, click.ClickedAt, nullString(click.Referrer),
+			nullString(click.ReferrerDomain), nullString(click.UserAgent), nullString(click.IP), nullString(click.Variant),
+			nullString(click.Browser), nullString(click.OS), nullString(click.DeviceType), click.IsBot,
+			nullString(click.Country), nullString(click.Region), nullString(click.City))
+	}
+	query.WriteString(` ON CONFLICT (event_id, clicked_at) DO NOTHING`)
+
+	_, err := r.db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// GetClickStats aggregates clicks in [q.From, q.To). Bots are excluded
+// unless q.IncludeBots is set. Buckets already rolled up are read from the
+// daily and hourly rollups; only the edges of the range and the clicks
+// newer than the rollup watermark are read from raw events.
+func (r *ClickRepository) GetClickStats(q ClickStatsQuery) (*ClickStats, error) {
+	defer observeQuery("GetClickStats", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watermark, err := r.rollupWatermark(ctx)
+	if err != nil {
+		r.logger.Error("GetClickStats watermark", "short_code", q.ShortCode, "error", err)
+		return nil, fmt.Errorf("repository: GetClickStats: %w", err)
+	}
+
+	seg := splitStatsRange(q.From, q.To, watermark, q.Bucket == "hour")
+
+	query := `WITH source AS (
+			SELECT bucket AS t, dimension, value, clicks
+			FROM click_rollups_daily
+			WHERE short_code = $1 AND bucket >= $2 AND bucket < $3 AND ($12 OR NOT is_bot)
+			UNION ALL
+			SELECT bucket, dimension, value, clicks
+			FROM click_rollups_hourly
+			WHERE short_code = $1 AND ((bucket >= $4 AND bucket < $5) OR (bucket >= $6 AND bucket < $7))
+				AND ($12 OR NOT is_bot)
+			UNION ALL
+			SELECT c.clicked_at, d.dimension, d.value, 1
+			FROM clicks c
+			` + rollupDimensions + `
+			WHERE c.short_code = $1 AND ((c.clicked_at >= $8 AND c.clicked_at < $9) OR (c.clicked_at >= $10 AND c.clicked_at < $11))
+				AND ($12 OR NOT c.is_bot)
+		), bucketed AS (
+			SELECT date_trunc($13, t AT TIME ZONE 'UTC') AS bucket, dimension, value, clicks
+			FROM source
+		)
+		SELECT dimension, COALESCE(value, ''), bucket, SUM(clicks)::BIGINT, GROUPING(bucket) = 1
+		FROM bucketed
+		GROUP BY GROUPING SETS ((dimension, value), (dimension, bucket))
+		HAVING (GROUPING(bucket) = 0 AND dimension = 'total') OR (GROUPING(bucket) = 1 AND dimension <> 'total')`
+
+	rows, err := r.db.QueryContext(ctx, query, q.ShortCode,
+		seg.daily[0], seg.daily[1],
+		seg.hourly[0][0], seg.hourly[0][1], seg.hourly[1][0], seg.hourly[1][1],
+		seg.raw[0][0], seg.raw[0][1], seg.raw[1][0], seg.raw[1][1],
+		q.IncludeBots, q.Bucket)
+	if err != nil {
+		r.logger.Error("GetClickStats", "short_code", q.ShortCode, "error", err)
+		return nil, fmt.Errorf("repository: GetClickStats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats ClickStats
+	breakdowns := make(map[string][]DimensionCount)
+
+	for rows.Next() {
+		var dimension, value string
+		var bucket sql.NullTime
+		var count int64
+		var isBreakdown bool
+
+		if err := rows.Scan(&dimension, &value, &bucket, &count, &isBreakdown); err != nil {
+			r.logger.Error("GetClickStats scan", "error", err)
+			return nil, err
+		}
+
+		if isBreakdown {
+			breakdowns[dimension] = append(breakdowns[dimension], DimensionCount{Value: value, Count: count})
+			continue
+		}
+
+		stats.Total += count
+		stats.Series = append(stats.Series, ClickBucket{Start: bucket.Time.UTC(), Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("GetClickStats rows", "error", err)
+		return nil, err
+	}
+
+	sort.Slice(stats.Series, func(i, j int) bool {
+		return stats.Series[i].Start.Before(stats.Series[j].Start)
+	})
+
+	stats.Referrers = topDimension(breakdowns["referrer_domain"])
+	stats.Browsers = topDimension(breakdowns["browser"])
+	stats.OS = topDimension(breakdowns["os"])
+	stats.Devices = topDimension(breakdowns["device_type"])
+	stats.Countries = topDimension(breakdowns["country"])
+	stats.Regions = topDimension(breakdowns["region"])
+	stats.Cities = topDimension(breakdowns["city"])
+
+	return &stats, nil
+}
+
+// statsSegments are the half-open time ranges a stats query reads from each
+// source. Empty ranges have equal bounds.
+type statsSegments struct {
+	daily  [2]time.Time
+	hourly [2][2]time.Time
+	raw    [2][2]time.Time
+}
+
+// splitStatsRange divides [from, to) so that whole days before the rollup
+// watermark come from daily rollups, whole hours around them from hourly
+// rollups, and everything else from raw clicks. Hourly series never use
+// daily rollups.
+func splitStatsRange(from, to, watermark time.Time, hourly bool) statsSegments {
+	split := clampTime(watermark.Truncate(time.Hour), from, to)
+	hourStart := clampTime(ceilTime(from, time.Hour), from, split)
+
+	dayStart, dayEnd := split, split
+	if !hourly {
+		dayStart = clampTime(ceilTime(hourStart, 24*time.Hour), hourStart, split)
+		dayEnd = clampTime(split.Truncate(24*time.Hour), dayStart, split)
+	}
+
+	return statsSegments{
+		daily:  [2]time.Time{dayStart, dayEnd},
+		hourly: [2][2]time.Time{{hourStart, dayStart}, {dayEnd, split}},
+		raw:    [2][2]time.Time{{from, hourStart}, {split, to}},
+	}
+}
+
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	if c := t.Truncate(d); c.Before(t) {
+		return c.Add(d)
+	}
+	return t
+}
+
+func clampTime(t, lo, hi time.Time) time.Time {
+	if t.Before(lo) {
+		return lo
+	}
+	if t.After(hi) {
+		return hi
+	}
+	return t
+}
+
+// topDimension orders counts by descending count and keeps the largest.
+func topDimension(counts []DimensionCount) []DimensionCount {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+
+	if len(counts) > breakdownLimit {
+		counts = counts[:breakdownLimit]
+	}
+	return counts
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitStatsRange(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	from := at("2025-03-01T10:30:00Z")
+	to := at("2025-03-05T12:00:00Z")
+	watermark := at("2025-03-04T08:20:00Z")
+
+	seg := splitStatsRange(from, to, watermark, false)
+
+	want := statsSegments{
+		daily: [2]time.Time{at("2025-03-02T00:00:00Z"), at("2025-03-04T00:00:00Z")},
+		hourly: [2][2]time.Time{
+			{at("2025-03-01T11:00:00Z"), at("2025-03-02T00:00:00Z")},
+			{at("2025-03-04T00:00:00Z"), at("2025-03-04T08:00:00Z")},
+		},
+		raw: [2][2]time.Time{
+			{from, at("2025-03-01T11:00:00Z")},
+			{at("2025-03-04T08:00:00Z"), to},
+		},
+	}
+	if seg != want {
+		t.Errorf("splitStatsRange() = %+v, want %+v", seg, want)
+	}
+
+	seg = splitStatsRange(from, to, watermark, true)
+	if seg.daily[0] != seg.daily[1] {
+		t.Errorf("hourly series read daily rollups: %v", seg.daily)
+	}
+	if seg.hourly[0] != [2]time.Time{at("2025-03-01T11:00:00Z"), at("2025-03-04T08:00:00Z")} {
+		t.Errorf("hourly segment = %v", seg.hourly[0])
+	}
+
+	// Rollups that never ran leave everything to raw clicks.
+	seg = splitStatsRange(from, to, time.Time{}, false)
+	if seg.raw[0] != [2]time.Time{from, from} || seg.raw[1] != [2]time.Time{from, to} {
+		t.Errorf("without rollups raw = %v", seg.raw)
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const clickCountsKey = "clicks:counts"
+
+// RedisCounterBuffer buffers click counts in a Redis hash shared by all app
+// instances, so counts survive app restarts between flushes.
+type RedisCounterBuffer struct {
+	redis redis.UniversalClient
+}
+
+func NewRedisCounterBuffer(redis redis.UniversalClient) *RedisCounterBuffer {
+	return &RedisCounterBuffer{
+		redis: redis,
+	}
+}
+
+func (b *RedisCounterBuffer) Incr(ctx context.Context, shortCode string) error {
+	if err := b.redis.HIncrBy(ctx, clickCountsKey, shortCode, 1).Err(); err != nil {
+		return fmt.Errorf("redis: hincrby %s: %w", clickCountsKey, err)
+	}
+	return nil
+}
+
+func (b *RedisCounterBuffer) Drain(ctx context.Context) (map[string]int64, error) {
+	var values *redis.MapStringStringCmd
+
+	_, err := b.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.HGetAll(ctx, clickCountsKey)
+		pipe.Del(ctx, clickCountsKey)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: drain %s: %w", clickCountsKey, err)
+	}
+
+	counts := make(map[string]int64, len(values.Val()))
+	for shortCode, value := range values.Val() {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[shortCode] = count
+	}
+
+	return counts, nil
+}
+
+func (b *RedisCounterBuffer) Restore(ctx context.Context, counts map[string]int64) error {
+	_, err := b.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for shortCode, count := range counts {
+			pipe.HIncrBy(ctx, clickCountsKey, shortCode, count)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: restore %s: %w", clickCountsKey, err)
+	}
+	return nil
+}
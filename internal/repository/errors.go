@@ -3,5 +3,9 @@ package repository
 import "errors"
 
 var (
-	ErrNotFound = errors.New("url not found")
+	ErrNotFound  = errors.New("url not found")
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrCacheUnavailable is returned without calling the cache while it is
+	// considered unhealthy.
+	ErrCacheUnavailable = errors.New("cache unavailable")
 )
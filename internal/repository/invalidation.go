@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ShortCodeChannel broadcasts short codes to every app instance through
+// Redis Pub/Sub.
+type ShortCodeChannel struct {
+	redis   redis.UniversalClient
+	channel string
+}
+
+// NewCacheInvalidations tells instances to drop short codes from their
+// in-process cache. It publishes on a channel under the cache key prefix:
+// Pub/Sub channels are not scoped to a database, so the prefix is what keeps
+// deployments sharing a Redis server apart.
+func NewCacheInvalidations(redis redis.UniversalClient, prefix string) *ShortCodeChannel {
+	return &ShortCodeChannel{
+		redis:   redis,
+		channel: prefix + "invalidate",
+	}
+}
+
+// NewCodeAnnouncements tells instances about newly created short codes so
+// they can add them to their code filter.
+func NewCodeAnnouncements(redis redis.UniversalClient, prefix string) *ShortCodeChannel {
+	return &ShortCodeChannel{
+		redis:   redis,
+		channel: prefix + "created",
+	}
+}
+
+func (c *ShortCodeChannel) Publish(ctx context.Context, shortCode string) error {
+	if err := c.redis.Publish(ctx, c.channel, shortCode).Err(); err != nil {
+		return fmt.Errorf("redis: publish %s: %w", c.channel, err)
+	}
+	return nil
+}
+
+// Subscribe calls handle for every published short code until ctx is done.
+// Codes published while the connection is down are lost, so reset is called
+// every time the subscription is (re)established.
+func (c *ShortCodeChannel) Subscribe(ctx context.Context, handle func(shortCode string), reset func()) error {
+	sub := c.redis.Subscribe(ctx, c.channel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("redis: subscribe %s: %w", c.channel, err)
+	}
+
+	messages := sub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				reset()
+			case *redis.Message:
+				handle(msg.Payload)
+			}
+		}
+	}
+}
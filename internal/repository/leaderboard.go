@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardWindow is a sliding window made of fixed time buckets. The
+// current, partial bucket is included, so a window covers between
+// (Buckets-1)*Bucket and Buckets*Bucket of clicks.
+type LeaderboardWindow struct {
+	Bucket  time.Duration
+	Buckets int
+}
+
+var LeaderboardWindows = map[string]LeaderboardWindow{
+	"hour": {Bucket: 5 * time.Minute, Buckets: 12},
+	"day":  {Bucket: time.Hour, Buckets: 24},
+	"week": {Bucket: 24 * time.Hour, Buckets: 7},
+}
+
+type LeaderboardEntry struct {
+	ShortCode string
+	Clicks    int64
+	// PreviousClicks is only filled for trending queries.
+	PreviousClicks int64
+}
+
+// trendingCandidates bounds how many of the current window's top links are
+// compared against the previous window.
+const trendingCandidates = 200
+
+// LeaderboardRepository keeps per-bucket click counts in Redis sorted sets,
+// one set per window granularity and bucket. Buckets expire once they can no
+// longer be part of a current or previous window.
+type LeaderboardRepository struct {
+	redis    redis.UniversalClient
+	hashTags bool
+}
+
+func NewLeaderboardRepository(redis redis.UniversalClient) *LeaderboardRepository {
+	return &LeaderboardRepository{
+		redis: redis,
+	}
+}
+
+// WithHashTags names keys with the bucket size as a hash tag, which Redis
+// Cluster needs to union the buckets of a window in one command.
+func (r *LeaderboardRepository) WithHashTags() *LeaderboardRepository {
+	r.hashTags = true
+	return r
+}
+
+func (r *LeaderboardRepository) Record(ctx context.Context, shortCode string, at time.Time) error {
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, w := range LeaderboardWindows {
+			key := leaderboardKey(w.Bucket, at.Truncate(w.Bucket), r.hashTags)
+			pipe.ZIncrBy(ctx, key, 1, shortCode)
+			// Keep two full windows for trending comparisons.
+			pipe.Expire(ctx, key, time.Duration(2*w.Buckets+1)*w.Bucket)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: leaderboard record %s: %w", shortCode, err)
+	}
+	return nil
+}
+
+// Top returns the most clicked links in the window ending at at.
+func (r *LeaderboardRepository) Top(ctx context.Context, w LeaderboardWindow, at time.Time, limit int) ([]LeaderboardEntry, error) {
+	dest := leaderboardUnionKey(w, "top", r.hashTags)
+
+	var top *redis.ZSliceCmd
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, dest, &redis.ZStore{Keys: leaderboardKeys(w, at, 0, r.hashTags)})
+		top = pipe.ZRevRangeWithScores(ctx, dest, 0, int64(limit-1))
+		pipe.Del(ctx, dest)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: leaderboard top: %w", err)
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(top.Val()))
+	for _, z := range top.Val() {
+		entries = append(entries, LeaderboardEntry{ShortCode: z.Member.(string), Clicks: int64(z.Score)})
+	}
+	return entries, nil
+}
+
+// Trending compares the window ending at at with the window before it. The
+// current top links are returned with their clicks in both windows; ranking
+// is left to the caller.
+func (r *LeaderboardRepository) Trending(ctx context.Context, w LeaderboardWindow, at time.Time) ([]LeaderboardEntry, error) {
+	current := leaderboardUnionKey(w, "current", r.hashTags)
+	// The previous window is read after EXEC, so it needs a key of its own.
+	previous := leaderboardUnionKey(w, "previous:"+rand.Text(), r.hashTags)
+
+	var top *redis.ZSliceCmd
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, current, &redis.ZStore{Keys: leaderboardKeys(w, at, 0, r.hashTags)})
+		pipe.ZUnionStore(ctx, previous, &redis.ZStore{Keys: leaderboardKeys(w, at, w.Buckets, r.hashTags)})
+		pipe.Expire(ctx, previous, time.Minute)
+		top = pipe.ZRevRangeWithScores(ctx, current, 0, trendingCandidates-1)
+		pipe.Del(ctx, current)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: leaderboard trending: %w", err)
+	}
+	defer r.redis.Del(context.WithoutCancel(ctx), previous)
+
+	if len(top.Val()) == 0 {
+		return nil, nil
+	}
+
+	members := make([]string, len(top.Val()))
+	for i, z := range top.Val() {
+		members[i] = z.Member.(string)
+	}
+
+	before, err := r.redis.ZMScore(ctx, previous, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: leaderboard trending previous: %w", err)
+	}
+
+	entries := make([]LeaderboardEntry, len(members))
+	for i, z := range top.Val() {
+		entries[i] = LeaderboardEntry{ShortCode: members[i], Clicks: int64(z.Score), PreviousClicks: int64(before[i])}
+	}
+	return entries, nil
+}
+
+// leaderboardKeys lists the bucket keys of the window ending at at, shifted
+// back by offset buckets.
+func leaderboardKeys(w LeaderboardWindow, at time.Time, offset int, tagged bool) []string {
+	end := at.Truncate(w.Bucket).Add(-time.Duration(offset) * w.Bucket)
+
+	keys := make([]string, w.Buckets)
+	for i := range keys {
+		keys[i] = leaderboardKey(w.Bucket, end.Add(-time.Duration(i)*w.Bucket), tagged)
+	}
+	return keys
+}
+
+func leaderboardKey(bucket time.Duration, start time.Time, tagged bool) string {
+	return leaderboardPrefix(bucket, tagged) + ":" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// leaderboardUnionKey names a scratch key a query unions buckets into.
+func leaderboardUnionKey(w LeaderboardWindow, name string, tagged bool) string {
+	return leaderboardPrefix(w.Bucket, tagged) + ":" + name
+}
+
+// leaderboardPrefix is shared by the keys of one bucket size, tagged so they
+// hash to the same Redis Cluster slot.
+func leaderboardPrefix(bucket time.Duration, tagged bool) string {
+	size := strconv.Itoa(int(bucket.Seconds()))
+	if tagged {
+		size = "{" + size + "}"
+	}
+	return "leaderboard:" + size
+}
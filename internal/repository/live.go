@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const liveClicksChannel = "clicks:live"
+
+// ClickFeed broadcasts click events to every app instance through Redis
+// Pub/Sub. Delivery is best effort: instances that are not subscribed when a
+// click is published never see it.
+type ClickFeed struct {
+	redis redis.UniversalClient
+}
+
+func NewClickFeed(redis redis.UniversalClient) *ClickFeed {
+	return &ClickFeed{
+		redis: redis,
+	}
+}
+
+func (f *ClickFeed) Publish(ctx context.Context, click *Click) error {
+	payload, err := json.Marshal(click)
+	if err != nil {
+		return fmt.Errorf("redis: encode click: %w", err)
+	}
+
+	if err := f.redis.Publish(ctx, liveClicksChannel, payload).Err(); err != nil {
+		return fmt.Errorf("redis: publish %s: %w", liveClicksChannel, err)
+	}
+	return nil
+}
+
+// Subscribe calls handle for every published click until ctx is done. The
+// subscription is re-established by go-redis after connection failures.
+func (f *ClickFeed) Subscribe(ctx context.Context, handle func(*Click)) error {
+	sub := f.redis.Subscribe(ctx, liveClicksChannel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("redis: subscribe %s: %w", liveClicksChannel, err)
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var click Click
+			if err := json.Unmarshal([]byte(msg.Payload), &click); err != nil {
+				continue
+			}
+			handle(&click)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	clickPartitionPrefix = "clicks_p"
+	clickArchivePrefix   = "clicks_archive_"
+	clickDefaultTable    = "clicks_default"
+	partitionMonthLayout = "200601"
+)
+
+// ClickPartition is a monthly partition of the clicks table holding clicks
+// in [Month, Month+1 month).
+type ClickPartition struct {
+	Name  string
+	Month time.Time
+}
+
+func ClickPartitionFor(month time.Time) ClickPartition {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return ClickPartition{
+		Name:  clickPartitionPrefix + month.Format(partitionMonthLayout),
+		Month: month,
+	}
+}
+
+// ListClickPartitions returns the monthly partitions attached to clicks,
+// oldest first. The default partition is not included.
+func (r *ClickRepository) ListClickPartitions() ([]ClickPartition, error) {
+	defer observeQuery("ListClickPartitions", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT c.relname
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE i.inhparent = 'clicks'::regclass
+			ORDER BY c.relname`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.Error("ListClickPartitions", "error", err)
+		return nil, fmt.Errorf("repository: ListClickPartitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []ClickPartition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			r.logger.Error("ListClickPartitions scan", "error", err)
+			return nil, err
+		}
+
+		if !strings.HasPrefix(name, clickPartitionPrefix) {
+			continue
+		}
+		month, err := time.Parse(partitionMonthLayout, strings.TrimPrefix(name, clickPartitionPrefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, ClickPartition{Name: name, Month: month})
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("ListClickPartitions rows", "error", err)
+		return nil, err
+	}
+
+	return partitions, nil
+}
+
+// CreateClickPartition creates and attaches the partition p. Clicks for its
+// month that already landed in the default partition are moved into it
+// first, otherwise postgres refuses to attach it.
+func (r *ClickRepository) CreateClickPartition(p ClickPartition) error {
+	defer observeQuery("CreateClickPartition", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	name := pq.QuoteIdentifier(p.Name)
+	from := pq.QuoteLiteral(p.Month.Format(time.RFC3339))
+	to := pq.QuoteLiteral(p.Month.AddDate(0, 1, 0).Format(time.RFC3339))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: CreateClickPartition: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE ` + name + ` (LIKE clicks INCLUDING DEFAULTS)`,
+		`WITH moved AS (
+			DELETE FROM ` + clickDefaultTable + `
+			WHERE clicked_at >= ` + from + ` AND clicked_at < ` + to + `
+			RETURNING *
+		)
+		INSERT INTO ` + name + ` SELECT * FROM moved`,
+		`ALTER TABLE clicks ATTACH PARTITION ` + name + ` FOR VALUES FROM (` + from + `) TO (` + to + `)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			r.logger.Error("CreateClickPartition", "partition", p.Name, "error", err)
+			return fmt.Errorf("repository: CreateClickPartition %s: %w", p.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: CreateClickPartition %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (r *ClickRepository) DropClickPartition(p ClickPartition) error {
+	defer observeQuery("DropClickPartition", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DROP TABLE `+pq.QuoteIdentifier(p.Name)); err != nil {
+		r.logger.Error("DropClickPartition", "partition", p.Name, "error", err)
+		return fmt.Errorf("repository: DropClickPartition %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// ArchiveClickPartition detaches p from clicks and renames it, keeping its
+// rows out of every query on clicks. It returns the archive table name.
+func (r *ClickRepository) ArchiveClickPartition(p ClickPartition) (string, error) {
+	defer observeQuery("ArchiveClickPartition", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	archive := clickArchivePrefix + p.Month.Format(partitionMonthLayout)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("repository: ArchiveClickPartition: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`ALTER TABLE clicks DETACH PARTITION ` + pq.QuoteIdentifier(p.Name),
+		`ALTER TABLE ` + pq.QuoteIdentifier(p.Name) + ` RENAME TO ` + pq.QuoteIdentifier(archive),
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			r.logger.Error("ArchiveClickPartition", "partition", p.Name, "error", err)
+			return "", fmt.Errorf("repository: ArchiveClickPartition %s: %w", p.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("repository: ArchiveClickPartition %s: %w", p.Name, err)
+	}
+	return archive, nil
+}
+
+// CountDefaultClicksBefore counts the clicks older than before that landed in
+// the default partition, e.g. because no monthly partition existed yet.
+func (r *ClickRepository) CountDefaultClicksBefore(before time.Time) (int64, error) {
+	defer observeQuery("CountDefaultClicksBefore", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var count int64
+	query := `SELECT COUNT(*) FROM ` + clickDefaultTable + ` WHERE clicked_at < $1`
+	if err := r.db.QueryRowContext(ctx, query, before).Scan(&count); err != nil {
+		r.logger.Error("CountDefaultClicksBefore", "error", err)
+		return 0, fmt.Errorf("repository: CountDefaultClicksBefore: %w", err)
+	}
+	return count, nil
+}
+
+// ExpireDefaultClicks removes the clicks older than before from the default
+// partition. With archive set they are moved to the clicks_archive_default
+// table instead of being deleted, which keeps the indexes of clicks so it
+// can be queried like the detached partitions. It returns the number of rows removed.
+func (r *ClickRepository) ExpireDefaultClicks(before time.Time, archive bool) (int64, error) {
+	defer observeQuery("ExpireDefaultClicks", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("repository: ExpireDefaultClicks: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM ` + clickDefaultTable + ` WHERE clicked_at < $1`
+	if archive {
+		table := pq.QuoteIdentifier(clickArchivePrefix + "default")
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (LIKE clicks INCLUDING ALL)`); err != nil {
+			r.logger.Error("ExpireDefaultClicks", "error", err)
+			return 0, fmt.Errorf("repository: ExpireDefaultClicks: %w", err)
+		}
+		query = `WITH moved AS (` + query + ` RETURNING *) INSERT INTO ` + table + ` SELECT * FROM moved`
+	}
+
+	res, err := tx.ExecContext(ctx, query, before)
+	if err != nil {
+		r.logger.Error("ExpireDefaultClicks", "error", err)
+		return 0, fmt.Errorf("repository: ExpireDefaultClicks: %w", err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: ExpireDefaultClicks: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("repository: ExpireDefaultClicks: %w", err)
+	}
+	return removed, nil
+}
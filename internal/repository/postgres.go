@@ -8,14 +8,58 @@ import (
 	"log/slog"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/lib/pq"
 )
 
+const urlColumns = `id, short_code, original_url, risk_score, final_url, redirect_chain,
+	last_status, last_checked_at, check_failures, disabled, click_count, created_at`
+
 type URL struct {
-	ID          int
-	ShortCode   string
-	OriginalURL string
-	CreatedAt   time.Time
+	ID            int
+	ShortCode     string
+	OriginalURL   string
+	RiskScore     int
+	FinalURL      string
+	RedirectChain []string
+	LastStatus    int
+	LastCheckedAt time.Time
+	CheckFailures int
+	Disabled      bool
+	ClickCount    int64
+	CreatedAt     time.Time
+}
+
+type CheckResult struct {
+	Status    int
+	Broken    bool
+	CheckedAt time.Time
+	// DisableAfter disables the link once it has failed this many checks in
+	// a row. Zero never disables.
+	DisableAfter int
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanURL(row rowScanner) (*URL, error) {
+	var url URL
+	var finalURL sql.NullString
+	var lastStatus sql.NullInt64
+	var lastCheckedAt sql.NullTime
+
+	err := row.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.RiskScore,
+		&finalURL, pq.Array(&url.RedirectChain), &lastStatus, &lastCheckedAt,
+		&url.CheckFailures, &url.Disabled, &url.ClickCount, &url.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	url.FinalURL = finalURL.String
+	url.LastStatus = int(lastStatus.Int64)
+	url.LastCheckedAt = lastCheckedAt.Time
+
+	return &url, nil
 }
 
 type URLRepository struct {
@@ -31,77 +75,285 @@ func NewRepositoryPostgres(logger *slog.Logger, postgres *sql.DB) *URLRepository
 	}
 }
 
-func (r *URLRepository) CreateURL(shortCode, originalURL string) (*URL, error) {
+func (r *URLRepository) CreateURL(url *URL) (*URL, error) {
+	defer observeQuery("CreateURL", time.Now())
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var id int
 	var createdAt time.Time
 
-	query := `INSERT INTO urls (short_code, original_url) VALUES ($1, $2)
+	query := `INSERT INTO urls (short_code, original_url, risk_score, final_url, redirect_chain)
+			VALUES ($1, $2, $3, $4, $5)
 			RETURNING id, created_at`
 
-	err := r.db.QueryRowContext(ctx, query, shortCode, originalURL).Scan(&id, &createdAt)
+	err := r.db.QueryRowContext(ctx, query, url.ShortCode, url.OriginalURL, url.RiskScore,
+		nullString(url.FinalURL), pq.Array(url.RedirectChain)).Scan(&id, &createdAt)
 	if err != nil {
-		r.logger.Error("CreateURL", "original_url", originalURL, "short_code", shortCode, "id", id, "error", err)
+		r.logger.Error("CreateURL", "original_url", url.OriginalURL, "short_code", url.ShortCode, "id", id, "error", err)
 		return nil, fmt.Errorf("repository: CreateURL: %w", err)
 	}
 	return &URL{
-		ID:          id,
-		ShortCode:   shortCode,
-		OriginalURL: originalURL,
-		CreatedAt:   createdAt,
+		ID:            id,
+		ShortCode:     url.ShortCode,
+		OriginalURL:   url.OriginalURL,
+		RiskScore:     url.RiskScore,
+		FinalURL:      url.FinalURL,
+		RedirectChain: url.RedirectChain,
+		CreatedAt:     createdAt,
 	}, nil
 }
 
-func (r *URLRepository) GetURLByShortCode(shortCode string) (string, error) {
+func (r *URLRepository) GetURLByShortCode(shortCode string) (*URL, error) {
+	defer observeQuery("GetURLByShortCode", time.Now())
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var originalURL string
-	query := `SELECT original_url FROM urls 
+	query := `SELECT ` + urlColumns + ` FROM urls 
 			WHERE short_code = $1`
 
-	err := r.db.QueryRowContext(ctx, query, shortCode).Scan(&originalURL)
+	url, err := scanURL(r.db.QueryRowContext(ctx, query, shortCode))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrNotFound
+			return nil, ErrNotFound
 		}
 		r.logger.Error("GetURLByshortCode", "short_code", shortCode, "error", err)
-		return "", fmt.Errorf("repository: GetURLByShortCode: %w", err)
+		return nil, fmt.Errorf("repository: GetURLByShortCode: %w", err)
 	}
 
-	return originalURL, nil
+	return url, nil
 }
 
 func (r *URLRepository) GetAllURLS() ([]URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls ORDER BY
+	created_at DESC`
+
+	return r.queryURLs("GetAllURLS", query)
+}
+
+func (r *URLRepository) GetURLsByShortCodes(shortCodes []string) ([]URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+			WHERE short_code = ANY($1)`
+
+	return r.queryURLs("GetURLsByShortCodes", query, pq.Array(shortCodes))
+}
+
+const (
+	URLOrderClicks = "clicks"
+	URLOrderRecent = "recent"
+)
+
+// GetURLsByPopularity pages through enabled links, most clicked or most
+// recently created first. Pages are keyed on the last link of the previous
+// one, after, rather than an offset, so click counts changing between pages
+// do not shift rows onto a page already read; after is nil for the first
+// page.
+func (r *URLRepository) GetURLsByPopularity(order string, after *URL, limit int) ([]URL, error) {
+	key := "click_count"
+	if order == URLOrderRecent {
+		key = "created_at"
+	}
+
+	args := []any{limit}
+	where := "NOT disabled"
+	if after != nil {
+		var last any = after.ClickCount
+		if order == URLOrderRecent {
+			last = after.CreatedAt
+		}
+		args = append(args, last, after.ID)
+		where += " AND (" + key + ", id) < ($2, $3)"
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM urls
+			WHERE ` + where + `
+			ORDER BY ` + key + ` DESC, id DESC
+			LIMIT $1`
+
+	return r.queryURLs("GetURLsByPopularity", query, args...)
+}
+
+func (r *URLRepository) GetURLsDueForCheck(checkedBefore time.Time, limit int) ([]URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+			WHERE NOT disabled AND (last_checked_at IS NULL OR last_checked_at < $1)
+			ORDER BY last_checked_at NULLS FIRST
+			LIMIT $2`
+
+	return r.queryURLs("GetURLsDueForCheck", query, checkedBefore, limit)
+}
+
+func (r *URLRepository) GetBrokenURLs() ([]URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+			WHERE check_failures > 0
+			ORDER BY check_failures DESC, last_checked_at DESC`
+
+	return r.queryURLs("GetBrokenURLs", query)
+}
+
+// DeleteURL removes a link. Its clicks and rollups are kept.
+func (r *URLRepository) DeleteURL(shortCode string) error {
+	defer observeQuery("DeleteURL", time.Now())
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	query := `SELECT id, short_code, original_url, created_at FROM urls ORDER BY
-	created_at DESC`
+	res, err := r.db.ExecContext(ctx, `DELETE FROM urls WHERE short_code = $1`, shortCode)
+	if err != nil {
+		r.logger.Error("DeleteURL", "short_code", shortCode, "error", err)
+		return fmt.Errorf("repository: DeleteURL: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ForEachShortCode calls fn with every short code in the table. Rows are
+// streamed, so fn must not query the database itself.
+func (r *URLRepository) ForEachShortCode(fn func(shortCode string)) error {
+	defer observeQuery("ForEachShortCode", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT short_code FROM urls`)
+	if err != nil {
+		r.logger.Error("ForEachShortCode", "error", err)
+		return fmt.Errorf("repository: ForEachShortCode: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortCode string
+		if err := rows.Scan(&shortCode); err != nil {
+			r.logger.Error("ForEachShortCode scan", "error", err)
+			return err
+		}
+		fn(shortCode)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("ForEachShortCode rows", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCheckResult records the outcome of a destination check and reports
+// whether the link is disabled afterwards.
+func (r *URLRepository) UpdateCheckResult(id int, result CheckResult) (bool, error) {
+	defer observeQuery("UpdateCheckResult", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `UPDATE urls SET
+				last_status = $2,
+				last_checked_at = $3,
+				check_failures = CASE WHEN $4 THEN check_failures + 1 ELSE 0 END,
+				disabled = disabled OR ($4 AND $5 > 0 AND check_failures + 1 >= $5)
+			WHERE id = $1
+			RETURNING disabled`
+
+	status := sql.NullInt64{Int64: int64(result.Status), Valid: result.Status != 0}
+
+	var disabled bool
+	err := r.db.QueryRowContext(ctx, query, id, status, result.CheckedAt, result.Broken, result.DisableAfter).Scan(&disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrNotFound
+		}
+		r.logger.Error("UpdateCheckResult", "id", id, "error", err)
+		return false, fmt.Errorf("repository: UpdateCheckResult: %w", err)
+	}
+
+	return disabled, nil
+}
+
+func (r *URLRepository) CountBrokenURLs() (broken int, disabled int, err error) {
+	defer observeQuery("CountBrokenURLs", time.Now())
 
-	rows, err := r.db.QueryContext(ctx, query)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT
+				COUNT(*) FILTER (WHERE check_failures > 0 AND NOT disabled),
+				COUNT(*) FILTER (WHERE disabled)
+			FROM urls`
+
+	if err := r.db.QueryRowContext(ctx, query).Scan(&broken, &disabled); err != nil {
+		r.logger.Error("CountBrokenURLs", "error", err)
+		return 0, 0, fmt.Errorf("repository: CountBrokenURLs: %w", err)
+	}
+
+	return broken, disabled, nil
+}
+
+// IncrementClickCounts adds the buffered per short code click counts to the
+// persisted totals in a single batched update.
+func (r *URLRepository) IncrementClickCounts(counts map[string]int64) error {
+	defer observeQuery("IncrementClickCounts", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	codes := make([]string, 0, len(counts))
+	increments := make([]int64, 0, len(counts))
+	for shortCode, count := range counts {
+		codes = append(codes, shortCode)
+		increments = append(increments, count)
+	}
+
+	query := `UPDATE urls SET click_count = urls.click_count + c.count
+			FROM unnest($1::text[], $2::bigint[]) AS c(short_code, count)
+			WHERE urls.short_code = c.short_code`
+
+	if _, err := r.db.ExecContext(ctx, query, pq.Array(codes), pq.Array(increments)); err != nil {
+		r.logger.Error("IncrementClickCounts", "codes", len(codes), "error", err)
+		return fmt.Errorf("repository: IncrementClickCounts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *URLRepository) queryURLs(method, query string, args ...any) ([]URL, error) {
+	defer observeQuery(method, time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		r.logger.Error("GetAllURLS", "error", err)
-		return nil, fmt.Errorf("repository: GetAllURLS: %w", err)
+		r.logger.Error(method, "error", err)
+		return nil, fmt.Errorf("repository: %s: %w", method, err)
 	}
 	defer rows.Close()
 
 	var urls []URL
 	for rows.Next() {
-		var url URL
-		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.CreatedAt); err != nil {
-			r.logger.Error("GetAllURLS scan", "error", err)
+		url, err := scanURL(rows)
+		if err != nil {
+			r.logger.Error(method+" scan", "error", err)
 			return nil, err
 		}
-		urls = append(urls, url)
+		urls = append(urls, *url)
 	}
 
 	if err := rows.Err(); err != nil {
-		r.logger.Error("GetAllURLS rows", "error", err)
+		r.logger.Error(method+" rows", "error", err)
 		return nil, err
 	}
 
 	return urls, nil
 }
+
+// observeQuery records the latency of a repository method. Deferred with
+// time.Now() as the argument, so the start time is taken on entry.
+func observeQuery(method string, start time.Time) {
+	metrics.DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
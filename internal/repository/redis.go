@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,34 +12,205 @@ import (
 
 const (
 	defaultCacheTTL = 24 * time.Hour
+
+	// DeletedCacheTTL is the minimum lifetime of the missing entry left when
+	// a link is deleted or disabled. It must outlast a lookup that read the
+	// link from postgres before the change and fills the cache after it.
+	DeletedCacheTTL = time.Minute
+
+	// cacheValueVersion is bumped on incompatible changes to cachedURL.
+	// Readers treat values from newer versions as misses, so instances of
+	// different versions can share the cache during a rollout.
+	cacheValueVersion = 1
+
+	cacheStatusActive  = "active"
+	cacheStatusMissing = "missing"
 )
 
+// cachedURL is the value stored under a short code key. Legacy values, from
+// before versioning, are the bare destination.
+type cachedURL struct {
+	V           int       `json:"v"`
+	Status      string    `json:"status"`
+	Destination string    `json:"destination,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+}
+
+// decodeCachedURL parses a cached value. It returns nil for values it does
+// not understand.
+func decodeCachedURL(val string) *cachedURL {
+	switch {
+	case val == "":
+		return nil
+	case !strings.HasPrefix(val, "{"):
+		return &cachedURL{Status: cacheStatusActive, Destination: val}
+	}
+
+	var cached cachedURL
+	if err := json.Unmarshal([]byte(val), &cached); err != nil || cached.V > cacheValueVersion {
+		return nil
+	}
+	return &cached
+}
+
 type RedisRepository struct {
-	redis *redis.Client
+	redis  redis.UniversalClient
+	prefix string
+	legacy bool
 }
 
-func NewRedisRepository(redis *redis.Client) *RedisRepository {
+// NewRedisRepository stores links under prefix followed by the short code,
+// so the cache can share a Redis database with other data.
+func NewRedisRepository(redis redis.UniversalClient, prefix string) *RedisRepository {
 	return &RedisRepository{
-		redis: redis,
+		redis:  redis,
+		prefix: prefix,
+	}
+}
+
+// WithLegacyKeys keeps the cache compatible with instances that store bare
+// destinations under unprefixed keys, for the length of a rolling deploy:
+// new links are also written that way, and the unprefixed key is read when
+// the prefixed one is absent. Such instances read any value there as a
+// destination, so missing entries are never written to it, only cleared.
+// Turn it off once no such instance is left.
+func (r *RedisRepository) WithLegacyKeys() *RedisRepository {
+	r.legacy = true
+	return r
+}
+
+func (r *RedisRepository) key(shortCode string) string {
+	return r.prefix + shortCode
+}
+
+// writeKey returns the key an active or missing entry for shortCode is
+// written to, and in legacy mode the other key, which must be cleared so it
+// cannot shadow or outlive the entry.
+func (r *RedisRepository) writeKey(shortCode string, active bool) (key, other string) {
+	if !r.legacy || r.prefix == "" {
+		return r.key(shortCode), ""
+	}
+	if active {
+		return shortCode, r.key(shortCode)
+	}
+	return r.key(shortCode), shortCode
+}
+
+// write stores val under key and clears other.
+func (r *RedisRepository) write(ctx context.Context, key, other, val string, ttl time.Duration) error {
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, val, ttl)
+		if other != "" {
+			pipe.Del(ctx, other)
+		}
+		return nil
+	})
+	return err
+}
+
+func encodeCachedURL(status, originalURL string, ttl time.Duration) (string, error) {
+	cached := cachedURL{
+		V:           cacheValueVersion,
+		Status:      status,
+		Destination: originalURL,
+		ExpiresAt:   time.Now().Add(ttl).UTC().Truncate(time.Second),
 	}
+
+	val, err := json.Marshal(cached)
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
 }
 
 func (r *RedisRepository) Set(ctx context.Context, shortCode, originalURL string) error {
-	if err := r.redis.Set(ctx, shortCode, originalURL, defaultCacheTTL).Err(); err != nil {
+	key, other := r.writeKey(shortCode, true)
+
+	val := originalURL
+	if key == r.key(shortCode) {
+		var err error
+		if val, err = encodeCachedURL(cacheStatusActive, originalURL, defaultCacheTTL); err != nil {
+			return fmt.Errorf("redis: encode key %s: %w", shortCode, err)
+		}
+	}
+
+	if err := r.write(ctx, key, other, val, defaultCacheTTL); err != nil {
 		return fmt.Errorf("redis: failed to set key %s: %w", shortCode, err)
 	}
 	return nil
 }
 
 func (r *RedisRepository) Get(ctx context.Context, shortCode string) (string, error) {
-
-	val, err := r.redis.Get(ctx, shortCode).Result()
+	val, err := r.redis.Get(ctx, r.key(shortCode)).Result()
+	if err == redis.Nil && r.legacy && r.prefix != "" {
+		val, err = r.redis.Get(ctx, shortCode).Result()
+	}
 	if err != nil {
 		if err == redis.Nil {
-			return "", fmt.Errorf("redis: key not found: %s", shortCode)
+			return "", fmt.Errorf("redis: key not found: %s: %w", shortCode, ErrCacheMiss)
 		}
 		return "", fmt.Errorf("redis: can't get value by key %s: %w", shortCode, err)
 	}
 
-	return val, nil
+	cached := decodeCachedURL(val)
+	switch {
+	case cached == nil:
+		return "", fmt.Errorf("redis: unknown value format for key %s: %w", shortCode, ErrCacheMiss)
+	case !cached.ExpiresAt.IsZero() && time.Now().After(cached.ExpiresAt):
+		return "", fmt.Errorf("redis: key expired: %s: %w", shortCode, ErrCacheMiss)
+	case cached.Status == cacheStatusMissing:
+		return "", fmt.Errorf("redis: key cached as missing: %s: %w", shortCode, ErrNotFound)
+	case cached.Status != cacheStatusActive || cached.Destination == "":
+		return "", fmt.Errorf("redis: unknown status %q for key %s: %w", cached.Status, shortCode, ErrCacheMiss)
+	}
+
+	return cached.Destination, nil
+}
+
+// Fill caches a destination read from postgres unless the key was written in
+// the meantime. Deletes leave a missing entry behind, so a lookup racing
+// with a delete cannot restore the link. It always writes the prefixed key,
+// where missing entries live, even in legacy mode.
+func (r *RedisRepository) Fill(ctx context.Context, shortCode, originalURL string) error {
+	val, err := encodeCachedURL(cacheStatusActive, originalURL, defaultCacheTTL)
+	if err != nil {
+		return fmt.Errorf("redis: encode key %s: %w", shortCode, err)
+	}
+
+	if err := r.redis.SetNX(ctx, r.key(shortCode), val, defaultCacheTTL).Err(); err != nil {
+		return fmt.Errorf("redis: failed to fill key %s: %w", shortCode, err)
+	}
+	return nil
+}
+
+// SetMissing caches that shortCode does not exist for ttl, so repeated
+// lookups of unknown codes do not reach postgres. The entry lives under the
+// code's own key and is replaced when the code is created.
+func (r *RedisRepository) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	val, err := encodeCachedURL(cacheStatusMissing, "", ttl)
+	if err != nil {
+		return fmt.Errorf("redis: encode key %s: %w", shortCode, err)
+	}
+
+	key, other := r.writeKey(shortCode, false)
+	if err := r.write(ctx, key, other, val, ttl); err != nil {
+		return fmt.Errorf("redis: failed to set missing key %s: %w", shortCode, err)
+	}
+	return nil
+}
+
+// Delete removes the entry under both the prefixed and the unprefixed key,
+// so instances using either stop serving it.
+func (r *RedisRepository) Delete(ctx context.Context, shortCode string) error {
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.key(shortCode))
+		if r.prefix != "" {
+			pipe.Del(ctx, shortCode)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: failed to delete key %s: %w", shortCode, err)
+	}
+	return nil
 }
@@ -0,0 +1,57 @@
+package repository
+
+import "testing"
+
+func TestDecodeCachedURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		val         string
+		status      string
+		destination string
+		unknown     bool
+	}{
+		{name: "legacy destination", val: "https://example.com", status: cacheStatusActive, destination: "https://example.com"},
+		{name: "empty", val: "", unknown: true},
+		{name: "v1 destination", val: `{"v":1,"status":"active","destination":"https://example.com","redirect_type":302}`, status: cacheStatusActive, destination: "https://example.com"},
+		{name: "v1 missing", val: `{"v":1,"status":"missing","expires_at":"2026-10-19T12:00:00Z"}`, status: cacheStatusMissing},
+		{name: "newer version", val: `{"v":2,"status":"active","destination":"https://example.com"}`, unknown: true},
+		{name: "corrupt", val: `{"v":1,`, unknown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached := decodeCachedURL(tt.val)
+			if tt.unknown {
+				if cached != nil {
+					t.Fatalf("decodeCachedURL(%q) = %+v, want nil", tt.val, cached)
+				}
+				return
+			}
+			if cached == nil {
+				t.Fatalf("decodeCachedURL(%q) = nil", tt.val)
+			}
+			if cached.Status != tt.status || cached.Destination != tt.destination {
+				t.Errorf("decodeCachedURL(%q) = %+v, want status %q destination %q", tt.val, cached, tt.status, tt.destination)
+			}
+		})
+	}
+}
+
+func TestRedisRepository_LegacyKeys(t *testing.T) {
+	r := NewRedisRepository(nil, "url:")
+	for _, active := range []bool{true, false} {
+		if key, other := r.writeKey("abc123", active); key != "url:abc123" || other != "" {
+			t.Errorf("writeKey(active=%v) = %q, %q, want the prefixed key only", active, key, other)
+		}
+	}
+
+	r.WithLegacyKeys()
+	if key, other := r.writeKey("abc123", true); key != "abc123" || other != "url:abc123" {
+		t.Errorf("legacy writeKey(active) = %q, %q, want the bare key, clearing the prefixed one", key, other)
+	}
+	// Instances from before the prefix read any value under the bare key as
+	// a destination, so missing entries must only clear it.
+	if key, other := r.writeKey("abc123", false); key != "url:abc123" || other != "abc123" {
+		t.Errorf("legacy writeKey(missing) = %q, %q, want the prefixed key, clearing the bare one", key, other)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyMigration reports what MigrateRedisKeys did or, in a dry run, would do.
+type KeyMigration struct {
+	Renamed int
+	Merged  int
+	// Skipped lists old keys left in place because their new key already
+	// exists and the two cannot be merged.
+	Skipped []string
+}
+
+// MigrateRedisKeys renames the visitor, leaderboard and dead-letter keys to
+// the hash-tagged names used in cluster mode, ahead of moving the data to
+// Redis Cluster. Visitor HyperLogLogs whose new key already exists are
+// merged into it. It uses SCAN and RENAME, so it must run against the single
+// node or sentinel deployment holding the untagged keys, not against a
+// cluster.
+func MigrateRedisKeys(ctx context.Context, client redis.UniversalClient, dryRun bool) (*KeyMigration, error) {
+	res := &KeyMigration{}
+
+	renames := []struct {
+		pattern string
+		newKey  func(string) string
+		merge   bool
+	}{
+		{pattern: "visitors:*", newKey: tagSecondField, merge: true},
+		{pattern: "leaderboard:*", newKey: tagSecondField},
+		{pattern: clickDeadKey, newKey: func(string) string { return clickDeadTaggedKey }},
+	}
+
+	for _, rn := range renames {
+		iter := client.Scan(ctx, 0, rn.pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			oldKey := iter.Val()
+			newKey := rn.newKey(oldKey)
+			if newKey == "" || newKey == oldKey {
+				continue
+			}
+
+			if err := migrateKey(ctx, client, oldKey, newKey, rn.merge, dryRun, res); err != nil {
+				return res, err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return res, fmt.Errorf("redis: scan %s: %w", rn.pattern, err)
+		}
+	}
+
+	return res, nil
+}
+
+func migrateKey(ctx context.Context, client redis.UniversalClient, oldKey, newKey string, merge, dryRun bool, res *KeyMigration) error {
+	exists, err := client.Exists(ctx, newKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis: exists %s: %w", newKey, err)
+	}
+
+	switch {
+	case exists == 0:
+		if !dryRun {
+			if err := client.RenameNX(ctx, oldKey, newKey).Err(); err != nil {
+				return fmt.Errorf("redis: rename %s: %w", oldKey, err)
+			}
+		}
+		res.Renamed++
+	case merge:
+		if !dryRun {
+			_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.PFMerge(ctx, newKey, oldKey)
+				pipe.Del(ctx, oldKey)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("redis: merge %s: %w", oldKey, err)
+			}
+		}
+		res.Merged++
+	default:
+		res.Skipped = append(res.Skipped, oldKey)
+	}
+
+	return nil
+}
+
+// tagSecondField turns "prefix:field:rest" into "prefix:{field}:rest". Keys
+// that are already tagged are returned unchanged.
+func tagSecondField(key string) string {
+	if strings.Contains(key, "{") {
+		return key
+	}
+
+	prefix, rest, ok := strings.Cut(key, ":")
+	if !ok {
+		return ""
+	}
+	field, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return ""
+	}
+	return prefix + ":{" + field + "}:" + rest
+}
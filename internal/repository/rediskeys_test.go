@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagSecondField(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	bucket := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		"visitors:abc123:20261019":    visitorsKey("abc123", day, true),
+		"leaderboard:3600:1792411200": leaderboardKey(time.Hour, bucket, true),
+		"visitors:{abc123}:20261019":  "visitors:{abc123}:20261019",
+		"leaderboard:3600:top":        "leaderboard:{3600}:top",
+		"leaderboard":                 "",
+	}
+
+	for in, want := range tests {
+		if got := tagSecondField(in); got != want {
+			t.Errorf("tagSecondField(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedisKeyNames_UntaggedOutsideCluster(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	bucket := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		visitorsKey("abc123", day, false):                            "visitors:abc123:20261019",
+		leaderboardKey(time.Hour, bucket, false):                     "leaderboard:3600:1792411200",
+		leaderboardUnionKey(LeaderboardWindows["day"], "top", false): "leaderboard:3600:top",
+		NewClickStream(nil, 0).deadKey:                               "clicks:dead",
+		NewClickStream(nil, 0).WithHashTags().deadKey:                "{clicks:stream}:dead",
+	}
+
+	for got, want := range tests {
+		if got != want {
+			t.Errorf("key = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const rollupStateName = "clicks"
+
+// rollupDimensions expands a click into one row per stats dimension. The
+// "total" dimension carries the per-bucket click count.
+const rollupDimensions = `CROSS JOIN LATERAL (VALUES
+		('total', ''),
+		('referrer_domain', COALESCE(c.referrer_domain, '')),
+		('browser', COALESCE(c.browser, '')),
+		('os', COALESCE(c.os, '')),
+		('device_type', COALESCE(c.device_type, '')),
+		('country', COALESCE(c.country, '')),
+		('region', COALESCE(c.region, '')),
+		('city', COALESCE(c.city, ''))
+	) AS d(dimension, value)`
+
+type RollupResult struct {
+	Hours     int
+	Days      int
+	Watermark time.Time
+}
+
+// Rollup re-aggregates every hourly and daily bucket that received clicks
+// since the last run, keyed on ingestion time, so late events are folded
+// into the buckets they belong to. Buckets are rebuilt from scratch, which
+// makes runs idempotent. Clicks ingested in the last settle period are left
+// for the next run, so transactions still in flight are not skipped.
+func (r *ClickRepository) Rollup(settle time.Duration) (*RollupResult, error) {
+	defer observeQuery("Rollup", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repository: Rollup: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the state row serializes runners across instances.
+	var from time.Time
+	err = tx.QueryRowContext(ctx, `SELECT watermark FROM rollup_state WHERE name = $1 FOR UPDATE`, rollupStateName).Scan(&from)
+	if err != nil {
+		r.logger.Error("Rollup watermark", "error", err)
+		return nil, fmt.Errorf("repository: Rollup: %w", err)
+	}
+
+	to := time.Now().Add(-settle)
+	if !to.After(from) {
+		return &RollupResult{Watermark: from}, nil
+	}
+
+	res := &RollupResult{Watermark: to}
+
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+		count *int
+	}{
+		{"dirty hours", `CREATE TEMP TABLE rollup_dirty_hours ON COMMIT DROP AS
+			SELECT DISTINCT short_code, date_trunc('hour', clicked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket
+			FROM clicks
+			WHERE ingested_at > $1 AND ingested_at <= $2`, []any{from, to}, &res.Hours},
+		{"dirty days", `CREATE TEMP TABLE rollup_dirty_days ON COMMIT DROP AS
+			SELECT DISTINCT short_code, date_trunc('day', bucket AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket
+			FROM rollup_dirty_hours`, nil, &res.Days},
+		{"delete hourly", `DELETE FROM click_rollups_hourly h
+			USING rollup_dirty_hours d
+			WHERE h.short_code = d.short_code AND h.bucket = d.bucket`, nil, nil},
+		{"insert hourly", `INSERT INTO click_rollups_hourly (short_code, bucket, dimension, value, is_bot, clicks)
+			SELECT c.short_code, h.bucket, d.dimension, d.value, c.is_bot, COUNT(*)
+			FROM rollup_dirty_hours h
+			JOIN clicks c ON c.short_code = h.short_code
+				AND c.clicked_at >= h.bucket AND c.clicked_at < h.bucket + INTERVAL '1 hour'
+			` + rollupDimensions + `
+			GROUP BY c.short_code, h.bucket, d.dimension, d.value, c.is_bot`, nil, nil},
+		{"delete daily", `DELETE FROM click_rollups_daily r
+			USING rollup_dirty_days d
+			WHERE r.short_code = d.short_code AND r.bucket = d.bucket`, nil, nil},
+		{"insert daily", `INSERT INTO click_rollups_daily (short_code, bucket, dimension, value, is_bot, clicks)
+			SELECT h.short_code, d.bucket, h.dimension, h.value, h.is_bot, SUM(h.clicks)
+			FROM rollup_dirty_days d
+			JOIN click_rollups_hourly h ON h.short_code = d.short_code
+				AND h.bucket >= d.bucket AND h.bucket < d.bucket + INTERVAL '1 day'
+			GROUP BY h.short_code, d.bucket, h.dimension, h.value, h.is_bot`, nil, nil},
+	}
+
+	for _, step := range steps {
+		result, err := tx.ExecContext(ctx, step.query, step.args...)
+		if err != nil {
+			r.logger.Error("Rollup "+step.name, "error", err)
+			return nil, fmt.Errorf("repository: Rollup %s: %w", step.name, err)
+		}
+
+		if step.count != nil {
+			n, _ := result.RowsAffected()
+			*step.count = int(n)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE rollup_state SET watermark = $2 WHERE name = $1`, rollupStateName, to); err != nil {
+		r.logger.Error("Rollup update watermark", "error", err)
+		return nil, fmt.Errorf("repository: Rollup: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repository: Rollup commit: %w", err)
+	}
+
+	return res, nil
+}
+
+// rollupWatermark returns the ingestion time up to which rollups are
+// complete, or the zero time when rollups have never run.
+func (r *ClickRepository) rollupWatermark(ctx context.Context) (time.Time, error) {
+	var watermark time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT watermark FROM rollup_state WHERE name = $1`, rollupStateName).Scan(&watermark)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	return watermark, nil
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	clickStreamKey = "clicks:stream"
+	clickDeadKey   = "clicks:dead"
+	// clickDeadTaggedKey hashes to the same slot as clickStreamKey, so a
+	// message can be dead-lettered and acknowledged in one transaction on
+	// Redis Cluster.
+	clickDeadTaggedKey = "{clicks:stream}:dead"
+	clickStreamGroup   = "clicks-writers"
+	clickPayloadKey    = "click"
+)
+
+type StreamMessage struct {
+	ID    string
+	Click *Click
+	// Err is set when the payload could not be decoded.
+	Err        error
+	Deliveries int64
+	raw        map[string]any
+}
+
+type PendingMessage struct {
+	ID         string
+	Idle       time.Duration
+	Deliveries int64
+}
+
+// ClickStream carries click events from the redirect path to the click
+// worker through a Redis Stream consumed by a single consumer group.
+type ClickStream struct {
+	redis   redis.UniversalClient
+	maxLen  int64
+	deadKey string
+}
+
+func NewClickStream(redis redis.UniversalClient, maxLen int64) *ClickStream {
+	return &ClickStream{
+		redis:   redis,
+		maxLen:  maxLen,
+		deadKey: clickDeadKey,
+	}
+}
+
+// WithHashTags moves dead letters to a key in the stream's hash slot, which
+// Redis Cluster needs to dead-letter and acknowledge in one transaction.
+func (s *ClickStream) WithHashTags() *ClickStream {
+	s.deadKey = clickDeadTaggedKey
+	return s
+}
+
+func (s *ClickStream) Record(ctx context.Context, click *Click) error {
+	payload, err := json.Marshal(click)
+	if err != nil {
+		return fmt.Errorf("redis: encode click: %w", err)
+	}
+
+	err = s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: clickStreamKey,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]any{clickPayloadKey: payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("redis: xadd %s: %w", clickStreamKey, err)
+	}
+
+	return nil
+}
+
+func (s *ClickStream) EnsureGroup(ctx context.Context) error {
+	err := s.redis.XGroupCreateMkStream(ctx, clickStreamKey, clickStreamGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("redis: create group %s: %w", clickStreamGroup, err)
+	}
+	return nil
+}
+
+// Read returns up to count new messages for consumer, blocking for at most
+// block when the stream is empty.
+func (s *ClickStream) Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]StreamMessage, error) {
+	streams, err := s.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    clickStreamGroup,
+		Consumer: consumer,
+		Streams:  []string{clickStreamKey, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis: xreadgroup %s: %w", clickStreamKey, err)
+	}
+
+	var messages []StreamMessage
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			messages = append(messages, decodeStreamMessage(msg, 1))
+		}
+	}
+
+	return messages, nil
+}
+
+// Pending lists messages that were delivered to some consumer but have not
+// been acknowledged for at least minIdle.
+func (s *ClickStream) Pending(ctx context.Context, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	pending, err := s.redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: clickStreamKey,
+		Group:  clickStreamGroup,
+		Idle:   minIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: xpending %s: %w", clickStreamKey, err)
+	}
+
+	res := make([]PendingMessage, 0, len(pending))
+	for _, p := range pending {
+		res = append(res, PendingMessage{ID: p.ID, Idle: p.Idle, Deliveries: p.RetryCount})
+	}
+
+	return res, nil
+}
+
+// Claim transfers pending messages to consumer. Messages that were trimmed
+// from the stream in the meantime are not returned.
+func (s *ClickStream) Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []PendingMessage) ([]StreamMessage, error) {
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.ID)
+		deliveries[p.ID] = p.Deliveries + 1
+	}
+
+	claimed, err := s.redis.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   clickStreamKey,
+		Group:    clickStreamGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: xclaim %s: %w", clickStreamKey, err)
+	}
+
+	messages := make([]StreamMessage, 0, len(claimed))
+	for _, msg := range claimed {
+		messages = append(messages, decodeStreamMessage(msg, deliveries[msg.ID]))
+	}
+
+	return messages, nil
+}
+
+func (s *ClickStream) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.redis.XAck(ctx, clickStreamKey, clickStreamGroup, ids...).Err(); err != nil {
+		return fmt.Errorf("redis: xack %s: %w", clickStreamKey, err)
+	}
+	return nil
+}
+
+// DeadLetter copies msg to the dead-letter stream together with the reason
+// and acknowledges it in one transaction.
+func (s *ClickStream) DeadLetter(ctx context.Context, msg StreamMessage, reason string) error {
+	values := map[string]any{
+		"original_id": msg.ID,
+		"reason":      reason,
+		"deliveries":  msg.Deliveries,
+	}
+	for k, v := range msg.raw {
+		values[k] = v
+	}
+
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: s.deadKey, Values: values})
+		pipe.XAck(ctx, clickStreamKey, clickStreamGroup, msg.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: dead-letter %s: %w", msg.ID, err)
+	}
+
+	return nil
+}
+
+// Backlog reports how many entries the group has not read yet and how many
+// were read but not acknowledged.
+func (s *ClickStream) Backlog(ctx context.Context) (lag int64, pending int64, err error) {
+	groups, err := s.redis.XInfoGroups(ctx, clickStreamKey).Result()
+	if err != nil {
+		return 0, 0, fmt.Errorf("redis: xinfo groups %s: %w", clickStreamKey, err)
+	}
+
+	for _, g := range groups {
+		if g.Name == clickStreamGroup {
+			return g.Lag, g.Pending, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("redis: group %s not found", clickStreamGroup)
+}
+
+func decodeStreamMessage(msg redis.XMessage, deliveries int64) StreamMessage {
+	res := StreamMessage{ID: msg.ID, Deliveries: deliveries, raw: msg.Values}
+
+	payload, ok := msg.Values[clickPayloadKey].(string)
+	if !ok {
+		res.Err = fmt.Errorf("missing %q field", clickPayloadKey)
+		return res
+	}
+
+	var click Click
+	if err := json.Unmarshal([]byte(payload), &click); err != nil {
+		res.Err = fmt.Errorf("decode click: %w", err)
+		return res
+	}
+	if click.ShortCode == "" || click.ClickedAt.IsZero() {
+		res.Err = errors.New("click without short code or timestamp")
+		return res
+	}
+
+	click.EventID = msg.ID
+	res.Click = &click
+
+	return res
+}
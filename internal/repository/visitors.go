@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// VisitorRepository estimates unique visitors per link and day with Redis
+// HyperLogLogs. Range estimates merge the daily sketches, so a visitor seen
+// on several days is counted once.
+type VisitorRepository struct {
+	redis     redis.UniversalClient
+	retention time.Duration
+	hashTags  bool
+}
+
+func NewVisitorRepository(redis redis.UniversalClient, retention time.Duration) *VisitorRepository {
+	return &VisitorRepository{
+		redis:     redis,
+		retention: retention,
+	}
+}
+
+// WithHashTags names keys with the short code as a hash tag, which Redis
+// Cluster needs to count several days in one PFCOUNT.
+func (r *VisitorRepository) WithHashTags() *VisitorRepository {
+	r.hashTags = true
+	return r
+}
+
+func (r *VisitorRepository) Add(ctx context.Context, shortCode string, day time.Time, visitorID string) error {
+	key := visitorsKey(shortCode, day, r.hashTags)
+
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PFAdd(ctx, key, visitorID)
+		pipe.Expire(ctx, key, r.retention)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: pfadd %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (r *VisitorRepository) CountDaily(ctx context.Context, shortCode string, days []time.Time) ([]int64, error) {
+	cmds := make([]*redis.IntCmd, len(days))
+
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, day := range days {
+			cmds[i] = pipe.PFCount(ctx, visitorsKey(shortCode, day, r.hashTags))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: pfcount daily %s: %w", shortCode, err)
+	}
+
+	counts := make([]int64, len(days))
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+
+	return counts, nil
+}
+
+func (r *VisitorRepository) CountRange(ctx context.Context, shortCode string, days []time.Time) (int64, error) {
+	if len(days) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(days))
+	for i, day := range days {
+		keys[i] = visitorsKey(shortCode, day, r.hashTags)
+	}
+
+	count, err := r.redis.PFCount(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis: pfcount range %s: %w", shortCode, err)
+	}
+
+	return count, nil
+}
+
+// visitorsKey names the HyperLogLog of one link and day.
+func visitorsKey(shortCode string, day time.Time, tagged bool) string {
+	if tagged {
+		shortCode = "{" + shortCode + "}"
+	}
+	return "visitors:" + shortCode + ":" + day.UTC().Format("20060102")
+}
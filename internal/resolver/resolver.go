@@ -0,0 +1,191 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrRedirectLoop   = errors.New("redirect loop detected")
+	ErrSelfRedirect   = errors.New("redirect chain points back to this service")
+	ErrPrivateAddress = errors.New("destination resolves to a private address")
+)
+
+type Result struct {
+	FinalURL string
+	// Chain lists every visited URL, starting with the submitted one and
+	// ending with FinalURL.
+	Chain []string
+	// Truncated is set when the hop limit was reached before a non-redirect
+	// response was seen.
+	Truncated bool
+}
+
+func (r *Result) Hops() int {
+	return len(r.Chain) - 1
+}
+
+type Resolver struct {
+	client    *http.Client
+	maxHops   int
+	timeout   time.Duration
+	maxBytes  int64
+	selfHosts map[string]bool
+}
+
+func New(maxHops int, timeout time.Duration, maxBytes int64, allowPrivate bool, selfHosts ...string) *Resolver {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = rejectPrivate
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	hosts := make(map[string]bool)
+	for _, host := range selfHosts {
+		if host != "" {
+			hosts[strings.ToLower(host)] = true
+		}
+	}
+
+	return &Resolver{
+		client: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxHops:   maxHops,
+		timeout:   timeout,
+		maxBytes:  maxBytes,
+		selfHosts: hosts,
+	}
+}
+
+func (r *Resolver) Resolve(ctx context.Context, rawURL string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	current, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: parse %q: %w", rawURL, err)
+	}
+
+	res := &Result{}
+	visited := make(map[string]bool)
+
+	for {
+		if r.isSelf(current) {
+			return nil, fmt.Errorf("resolver: %s: %w", current, ErrSelfRedirect)
+		}
+
+		key := normalize(current)
+		if visited[key] {
+			return nil, fmt.Errorf("resolver: %s: %w", current, ErrRedirectLoop)
+		}
+		visited[key] = true
+		res.Chain = append(res.Chain, current.String())
+
+		if res.Hops() >= r.maxHops {
+			res.Truncated = true
+			break
+		}
+
+		next, err := r.follow(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+		current = next
+	}
+
+	res.FinalURL = res.Chain[len(res.Chain)-1]
+	return res, nil
+}
+
+// follow requests u and returns the absolute redirect target, or nil when
+// the response is not a redirect.
+func (r *Resolver) follow(ctx context.Context, u *url.URL) (*url.URL, error) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: request %s: %w", u, err)
+	}
+	req.Header.Set("User-Agent", "url-shortener-resolver/1.0")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	// The body is never used, but draining a bounded amount lets the
+	// connection be reused without downloading arbitrarily large pages.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, r.maxBytes))
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return nil, nil
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, nil
+	}
+
+	next, err := u.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: bad location %q from %s: %w", location, u, err)
+	}
+
+	return next, nil
+}
+
+func (r *Resolver) isSelf(u *url.URL) bool {
+	return r.selfHosts[strings.ToLower(u.Host)] || r.selfHosts[strings.ToLower(u.Hostname())]
+}
+
+func normalize(u *url.URL) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	n.Fragment = ""
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return n.String()
+}
+
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s: %w", address, ErrPrivateAddress)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestResolver(maxHops int, selfHosts ...string) *Resolver {
+	return New(maxHops, 2*time.Second, 1024, true, selfHosts...)
+}
+
+func TestResolver_FollowsChain(t *testing.T) {
+	final := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 1<<20)))
+	}))
+	defer final.Close()
+
+	shortener := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, final.URL+"/landing", http.StatusFound)
+		}
+	}))
+	defer shortener.Close()
+
+	res, err := newTestResolver(5).Resolve(context.Background(), shortener.URL+"/a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{shortener.URL + "/a", shortener.URL + "/b", final.URL + "/landing"}
+	if strings.Join(res.Chain, " ") != strings.Join(want, " ") {
+		t.Errorf("chain = %v, want %v", res.Chain, want)
+	}
+	if res.FinalURL != final.URL+"/landing" {
+		t.Errorf("final = %s, want %s", res.FinalURL, final.URL+"/landing")
+	}
+	if res.Truncated {
+		t.Error("chain should not be truncated")
+	}
+}
+
+func TestResolver_DetectsLoop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" {
+			http.Redirect(w, r, "/b", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/a", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestResolver(10).Resolve(context.Background(), srv.URL+"/a")
+	if !errors.Is(err, ErrRedirectLoop) {
+		t.Errorf("expected ErrRedirectLoop, got %v", err)
+	}
+}
+
+func TestResolver_RejectsSelf(t *testing.T) {
+	var self *httptest.Server
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, self.URL+"/abc123", http.StatusFound)
+	}))
+	defer other.Close()
+
+	self = httptest.NewServer(http.NotFoundHandler())
+	defer self.Close()
+
+	selfURL, _ := url.Parse(self.URL)
+
+	_, err := newTestResolver(10, selfURL.Host).Resolve(context.Background(), other.URL)
+	if !errors.Is(err, ErrSelfRedirect) {
+		t.Errorf("expected ErrSelfRedirect, got %v", err)
+	}
+}
+
+func TestResolver_HopLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	res, err := newTestResolver(3).Resolve(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Truncated || res.Hops() != 3 {
+		t.Errorf("expected truncated chain of 3 hops, got %d (truncated %v)", res.Hops(), res.Truncated)
+	}
+}
+
+func TestResolver_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	r := New(5, 100*time.Millisecond, 1024, true)
+	if _, err := r.Resolve(context.Background(), srv.URL); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestResolver_RejectsPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	r := New(5, time.Second, 1024, false)
+	if _, err := r.Resolve(context.Background(), srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected ErrPrivateAddress, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package scanner
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	DefaultThreshold = 50
+
+	// scoreIPHost flags a raw IP host on its own at the default threshold.
+	scoreIPHost          = DefaultThreshold
+	scoreHomoglyph       = 60
+	scorePunycode        = 10
+	scoreBrandSubdomain  = 30
+	scoreCredentialPath  = 25
+	scoreRedirectParam   = 10
+	scoreExcessRedirects = 30
+
+	maxEmbeddedRedirects = 2
+	maxRedirectHops      = 3
+)
+
+var defaultBrands = []string{
+	"google", "gmail", "youtube", "apple", "icloud", "microsoft", "outlook",
+	"office365", "live", "paypal", "amazon", "facebook", "instagram",
+	"whatsapp", "netflix", "github", "twitter", "linkedin", "dropbox",
+	"yandex", "sberbank", "tinkoff",
+}
+
+var credentialKeywords = []string{
+	"login", "log-in", "signin", "sign-in", "logon", "verify", "verification",
+	"account", "password", "passwd", "credential", "wp-login", "webscr",
+	"banking", "secure", "unlock", "reset",
+}
+
+var redirectParams = map[string]bool{
+	"url": true, "u": true, "redirect": true, "redirect_uri": true,
+	"redirect_url": true, "next": true, "continue": true, "goto": true,
+	"dest": true, "destination": true, "target": true, "return": true,
+	"returnurl": true, "return_to": true,
+}
+
+// confusables maps characters commonly used to imitate latin letters onto
+// the letter they imitate.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+	'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ɡ': 'g', 'ӏ': 'l', 'ո': 'n', 'ս': 'u',
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'τ': 't',
+	'à': 'a', 'á': 'a', 'â': 'a', 'ä': 'a', 'å': 'a', 'è': 'e', 'é': 'e',
+	'ê': 'e', 'ë': 'e', 'ì': 'i', 'í': 'i', 'ï': 'i', 'ò': 'o', 'ó': 'o',
+	'ô': 'o', 'ö': 'o', 'ù': 'u', 'ú': 'u', 'ü': 'u', 'ç': 'c', 'ñ': 'n',
+	'0': 'o', '1': 'l',
+}
+
+type Result struct {
+	Score   int
+	Reasons []string
+}
+
+func (r *Result) add(score int, reason string) {
+	r.Score += score
+	r.Reasons = append(r.Reasons, reason)
+}
+
+type Scanner struct {
+	brands    []string
+	threshold int
+}
+
+func New(threshold int) *Scanner {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	return &Scanner{
+		brands:    defaultBrands,
+		threshold: threshold,
+	}
+}
+
+func (s *Scanner) Flagged(score int) bool {
+	return score >= s.threshold
+}
+
+func (s *Scanner) Scan(rawURL string) Result {
+	var res Result
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return res
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if isIPHost(host) {
+		res.add(scoreIPHost, "ip_host")
+	} else {
+		s.scanHost(host, &res)
+	}
+
+	if keyword := credentialKeyword(u); keyword != "" {
+		res.add(scoreCredentialPath, "credential_path:"+keyword)
+	}
+
+	embedded := embeddedRedirects(u.Query())
+	switch {
+	case embedded >= maxEmbeddedRedirects:
+		res.add(scoreExcessRedirects, "excessive_redirects")
+	case embedded > 0:
+		res.add(scoreRedirectParam, "redirect_param")
+	}
+
+	return res
+}
+
+// ScanChain scores a resolved redirect chain: the riskiest hop counts, and
+// long chains are penalised on top of that.
+func (s *Scanner) ScanChain(chain []string) Result {
+	var res Result
+	for _, hop := range chain {
+		if hopRes := s.Scan(hop); hopRes.Score > res.Score {
+			res = hopRes
+		}
+	}
+
+	if len(chain)-1 > maxRedirectHops {
+		res.add(scoreExcessRedirects, "excessive_redirects")
+	}
+
+	return res
+}
+
+func (s *Scanner) scanHost(host string, res *Result) {
+	unicodeHost, err := idna.ToUnicode(host)
+	if err != nil {
+		unicodeHost = host
+	}
+
+	labels := strings.Split(unicodeHost, ".")
+	asciiLabels := strings.Split(host, ".")
+	if len(labels) != len(asciiLabels) {
+		return
+	}
+
+	punycode := false
+	for i, label := range labels {
+		skeleton := skeletonOf(label)
+		for _, brand := range s.brands {
+			if skeleton == brand && label != brand {
+				res.add(scoreHomoglyph, "homoglyph:"+brand)
+				return
+			}
+		}
+
+		if strings.HasPrefix(asciiLabels[i], "xn--") {
+			punycode = true
+		}
+	}
+
+	if punycode {
+		res.add(scorePunycode, "punycode")
+	}
+
+	// Brand names used as subdomains of an unrelated registrable domain,
+	// e.g. paypal.com.account-check.io.
+	if len(asciiLabels) > 2 {
+		registrable := asciiLabels[len(asciiLabels)-2]
+		for _, label := range asciiLabels[:len(asciiLabels)-2] {
+			for _, brand := range s.brands {
+				if label == brand && registrable != brand {
+					res.add(scoreBrandSubdomain, "brand_subdomain:"+brand)
+					return
+				}
+			}
+		}
+	}
+}
+
+func skeletonOf(label string) string {
+	var b strings.Builder
+	for _, r := range label {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isIPHost(host string) bool {
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return true
+	}
+
+	// Dotless decimal and hex forms (http://3232235777, http://0xc0a80001)
+	// are resolved as IPv4 addresses by most browsers.
+	digits := "0123456789"
+	if strings.HasPrefix(host, "0x") {
+		host = host[2:]
+		digits = "0123456789abcdef"
+	}
+
+	if host == "" {
+		return false
+	}
+	for _, r := range host {
+		if !strings.ContainsRune(digits, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func credentialKeyword(u *url.URL) string {
+	target := strings.ToLower(u.EscapedPath() + "?" + u.RawQuery)
+	for _, keyword := range credentialKeywords {
+		if strings.Contains(target, keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+func embeddedRedirects(query url.Values) int {
+	count := 0
+	for key, values := range query {
+		for _, value := range values {
+			lower := strings.ToLower(value)
+			if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//") {
+				count++
+				if nested, err := url.Parse(value); err == nil {
+					count += embeddedRedirects(nested.Query())
+				}
+			} else if redirectParams[strings.ToLower(key)] && strings.HasPrefix(lower, "/") {
+				count++
+			}
+		}
+	}
+	return count
+}
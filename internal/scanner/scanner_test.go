@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	s := New(DefaultThreshold)
+
+	tests := []struct {
+		name    string
+		url     string
+		flagged bool
+		reason  string
+	}{
+		{"plain", "https://example.com/articles/42", false, ""},
+		{"brand", "https://paypal.com/", false, ""},
+		{"cyrillic homoglyph", "https://xn--pypal-4ve.com/", true, "homoglyph:paypal"},
+		{"digit homoglyph", "https://g00gle.com/", true, "homoglyph:google"},
+		{"ip host", "http://192.168.10.4/", true, "ip_host"},
+		{"ip host with credential path", "http://203.0.113.5/login", true, "ip_host"},
+		{"ip host with login", "http://192.168.10.4/paypal/login.php", true, "credential_path:login"},
+		{"decimal ip", "http://3232235777/", true, "ip_host"},
+		{"brand subdomain", "https://paypal.com.account-check.io/signin", true, "brand_subdomain:paypal"},
+		{"redirect param", "https://example.com/?next=https://other.org", false, "redirect_param"},
+		{"nested redirects", "https://example.com/?u=https://a.org/?next=https://b.org", false, "excessive_redirects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Scan(tt.url)
+
+			if s.Flagged(res.Score) != tt.flagged {
+				t.Errorf("flagged = %v, want %v (score %d, reasons %v)", !tt.flagged, tt.flagged, res.Score, res.Reasons)
+			}
+
+			if tt.reason == "" && len(res.Reasons) != 0 {
+				t.Errorf("expected no reasons, got %v", res.Reasons)
+			}
+
+			if tt.reason != "" && !strings.Contains(strings.Join(res.Reasons, " "), tt.reason) {
+				t.Errorf("expected reason %q, got %v", tt.reason, res.Reasons)
+			}
+		})
+	}
+}
+
+func TestScanner_ScanChain(t *testing.T) {
+	s := New(DefaultThreshold)
+
+	res := s.ScanChain([]string{"https://bit.example/a", "https://t.example/b", "https://g00gle.com/"})
+	if !s.Flagged(res.Score) {
+		t.Errorf("expected chain ending at a homoglyph to be flagged, got %d %v", res.Score, res.Reasons)
+	}
+
+	res = s.ScanChain([]string{"https://a.example/", "https://b.example/", "https://c.example/", "https://d.example/", "https://e.example/"})
+	if !strings.Contains(strings.Join(res.Reasons, " "), "excessive_redirects") {
+		t.Errorf("expected excessive_redirects for a 4 hop chain, got %v", res.Reasons)
+	}
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/geoip"
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/useragent"
+)
+
+const (
+	maxStatsBuckets = 5000
+	maxVisitorDays  = 400
+)
+
+var ErrInvalidRange = errors.New("invalid stats range")
+
+type Click struct {
+	ShortCode string
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	// IP is the raw client address. It is anonymized before it is stored.
+	IP string
+	// Variant identifies which destination variant was served. It is empty
+	// while links have a single destination.
+	Variant string
+}
+
+type StatsQuery struct {
+	ShortCode string
+	From      time.Time
+	To        time.Time
+	Bucket    string
+	// IncludeBots counts clicks classified as bots; they are excluded by
+	// default.
+	IncludeBots bool
+}
+
+type Stats struct {
+	ShortCode        string
+	From             time.Time
+	To               time.Time
+	Bucket           string
+	IncludeBots      bool
+	Total            int64
+	Series           []StatsBucket
+	Referrers        []StatsCount
+	Browsers         []StatsCount
+	OperatingSystems []StatsCount
+	Devices          []StatsCount
+	Countries        []StatsCount
+	Regions          []StatsCount
+	Cities           []StatsCount
+	// UniqueVisitors and DailyUniqueVisitors are HyperLogLog estimates and
+	// are only filled when unique visitor tracking is enabled.
+	UniqueVisitors      int64
+	DailyUniqueVisitors []StatsBucket
+}
+
+type StatsBucket struct {
+	Start time.Time
+	Count int64
+}
+
+type StatsCount struct {
+	Value string
+	Count int64
+}
+
+type URLLookup interface {
+	GetURLByShortCode(shortCode string) (*repository.URL, error)
+	GetURLsByShortCodes(shortCodes []string) ([]repository.URL, error)
+}
+
+type ClickStatsRepository interface {
+	GetClickStats(q repository.ClickStatsQuery) (*repository.ClickStats, error)
+}
+
+// ClickSink receives click events from the redirect path: either the Redis
+// stream drained by the click worker, or postgres directly.
+type ClickSink interface {
+	Record(ctx context.Context, click *repository.Click) error
+}
+
+type ClickBuffer interface {
+	Add(shortCode string)
+}
+
+type UniqueVisitors interface {
+	Add(ctx context.Context, shortCode string, day time.Time, visitorID string) error
+	CountDaily(ctx context.Context, shortCode string, days []time.Time) ([]int64, error)
+	CountRange(ctx context.Context, shortCode string, days []time.Time) (int64, error)
+}
+
+type UserAgentClassifier interface {
+	Classify(userAgent string) useragent.Info
+}
+
+type HotLinkTracker interface {
+	Add(shortCode string)
+}
+
+type ClickPublisher interface {
+	Publish(ctx context.Context, click *repository.Click) error
+}
+
+type GeoLocator interface {
+	Lookup(ip string) geoip.Location
+}
+
+type AnalyticsService struct {
+	urls        URLLookup
+	stats       ClickStatsRepository
+	sink        ClickSink
+	counter     ClickBuffer
+	visitors    UniqueVisitors
+	visitorSalt string
+	classifier  UserAgentClassifier
+	geo         GeoLocator
+	hotLinks    HotLinkTracker
+	leaderboard Leaderboard
+	publisher   ClickPublisher
+	logger      *slog.Logger
+
+	tracking        chan Click
+	trackingMu      sync.RWMutex
+	trackingStopped bool
+}
+
+func NewAnalyticsService(urls URLLookup, stats ClickStatsRepository, sink ClickSink, counter ClickBuffer, logger *slog.Logger) *AnalyticsService {
+	return &AnalyticsService{
+		urls:    urls,
+		stats:   stats,
+		sink:    sink,
+		counter: counter,
+		logger:  logger,
+	}
+}
+
+// WithUniqueVisitors enables unique visitor estimates. Visitors are
+// identified by a salted hash of their address and user agent; salt must be
+// kept secret and stable across instances.
+func (s *AnalyticsService) WithUniqueVisitors(visitors UniqueVisitors, salt string) *AnalyticsService {
+	s.visitors = visitors
+	s.visitorSalt = salt
+	return s
+}
+
+// WithUserAgentClassifier records browser, OS, device and bot flag on each
+// click. Without a classifier no click is treated as a bot.
+func (s *AnalyticsService) WithUserAgentClassifier(classifier UserAgentClassifier) *AnalyticsService {
+	s.classifier = classifier
+	return s
+}
+
+// WithGeoIP records country, region and city on each click. The lookup uses
+// the raw address before it is anonymized.
+func (s *AnalyticsService) WithGeoIP(geo GeoLocator) *AnalyticsService {
+	s.geo = geo
+	return s
+}
+
+// WithHotLinks reports each click to an in-process top-K tracker exported
+// as metrics. Per-link totals live in postgres, not in Prometheus labels.
+func (s *AnalyticsService) WithHotLinks(hotLinks HotLinkTracker) *AnalyticsService {
+	s.hotLinks = hotLinks
+	return s
+}
+
+// WithClickPublisher broadcasts every click to the live click stream.
+func (s *AnalyticsService) WithClickPublisher(publisher ClickPublisher) *AnalyticsService {
+	s.publisher = publisher
+	return s
+}
+
+// TrackClick records a redirect. Failures are logged and never affect the
+// redirect itself. With async tracking, which the server always enables, the
+// click is only queued; otherwise it is recorded inline.
+func (s *AnalyticsService) TrackClick(click Click) {
+	if s.tracking == nil {
+		s.trackClick(click)
+		return
+	}
+	s.enqueueClick(click)
+}
+
+func (s *AnalyticsService) trackClick(click Click) {
+	s.counter.Add(click.ShortCode)
+	if s.hotLinks != nil {
+		s.hotLinks.Add(click.ShortCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var ua useragent.Info
+	if s.classifier != nil {
+		ua = s.classifier.Classify(click.UserAgent)
+	}
+
+	// Crawlers would inflate the visitor estimates, which cannot be filtered
+	// after the fact.
+	if s.visitors != nil && !ua.Bot {
+		visitorID := visitorFingerprint(s.visitorSalt, click.IP, click.UserAgent)
+		if err := s.visitors.Add(ctx, click.ShortCode, click.ClickedAt, visitorID); err != nil {
+			s.logger.Error("TrackClick: unique visitors", "short_code", click.ShortCode, "error", err)
+		}
+	}
+
+	var location geoip.Location
+	if s.geo != nil {
+		location = s.geo.Lookup(click.IP)
+	}
+
+	if s.leaderboard != nil && !ua.Bot {
+		if err := s.leaderboard.Record(ctx, click.ShortCode, click.ClickedAt); err != nil {
+			s.logger.Error("TrackClick: leaderboard", "short_code", click.ShortCode, "error", err)
+		}
+	}
+
+	event := &repository.Click{
+		ShortCode:      click.ShortCode,
+		ClickedAt:      click.ClickedAt,
+		Referrer:       click.Referrer,
+		ReferrerDomain: referrerDomain(click.Referrer),
+		UserAgent:      click.UserAgent,
+		IP:             anonymizeIP(click.IP),
+		Variant:        click.Variant,
+		Browser:        ua.Browser,
+		OS:             ua.OS,
+		DeviceType:     ua.Device,
+		IsBot:          ua.Bot,
+		Country:        location.Country,
+		Region:         location.Region,
+		City:           location.City,
+	}
+
+	if s.publisher != nil {
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			s.logger.Error("TrackClick: publish", "short_code", click.ShortCode, "error", err)
+		}
+	}
+
+	if err := s.sink.Record(ctx, event); err != nil {
+		metrics.ClickEventsPublished.WithLabelValues("error").Inc()
+		s.logger.Error("TrackClick:", "short_code", click.ShortCode, "error", err)
+		return
+	}
+	metrics.ClickEventsPublished.WithLabelValues("ok").Inc()
+}
+
+func (s *AnalyticsService) GetStats(q StatsQuery) (*Stats, error) {
+	step, err := bucketStep(q.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := q.From.UTC(), q.To.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidRange)
+	}
+
+	buckets := 0
+	for t := truncateBucket(from, q.Bucket); t.Before(to); t = step(t) {
+		if buckets++; buckets > maxStatsBuckets {
+			return nil, fmt.Errorf("%w: more than %d %s buckets", ErrInvalidRange, maxStatsBuckets, q.Bucket)
+		}
+	}
+
+	if _, err := s.urls.GetURLByShortCode(q.ShortCode); err != nil {
+		return nil, err
+	}
+
+	raw, err := s.stats.GetClickStats(repository.ClickStatsQuery{
+		ShortCode:   q.ShortCode,
+		From:        from,
+		To:          to,
+		Bucket:      q.Bucket,
+		IncludeBots: q.IncludeBots,
+	})
+	if err != nil {
+		s.logger.Error("GetStats:", "short_code", q.ShortCode, "error", err)
+		return nil, err
+	}
+
+	stats := &Stats{
+		ShortCode:        q.ShortCode,
+		From:             from,
+		To:               to,
+		Bucket:           q.Bucket,
+		IncludeBots:      q.IncludeBots,
+		Total:            raw.Total,
+		Series:           fillBuckets(raw.Series, from, to, q.Bucket, step),
+		Referrers:        toStatsCounts(raw.Referrers),
+		Browsers:         toStatsCounts(raw.Browsers),
+		OperatingSystems: toStatsCounts(raw.OS),
+		Devices:          toStatsCounts(raw.Devices),
+		Countries:        toStatsCounts(raw.Countries),
+		Regions:          toStatsCounts(raw.Regions),
+		Cities:           toStatsCounts(raw.Cities),
+	}
+
+	if s.visitors != nil {
+		s.fillUniqueVisitors(stats)
+	}
+
+	return stats, nil
+}
+
+// fillUniqueVisitors adds visitor estimates to stats. Sketches older than the
+// visitor retention have expired, so only the most recent days are queried.
+// Failures leave the estimates empty rather than failing the request.
+func (s *AnalyticsService) fillUniqueVisitors(stats *Stats) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var days []time.Time
+	for t := truncateBucket(stats.From, "day"); t.Before(stats.To); t = t.AddDate(0, 0, 1) {
+		days = append(days, t)
+	}
+	if len(days) > maxVisitorDays {
+		days = days[len(days)-maxVisitorDays:]
+	}
+
+	daily, err := s.visitors.CountDaily(ctx, stats.ShortCode, days)
+	if err != nil {
+		s.logger.Error("GetStats: daily unique visitors", "short_code", stats.ShortCode, "error", err)
+		return
+	}
+
+	total, err := s.visitors.CountRange(ctx, stats.ShortCode, days)
+	if err != nil {
+		s.logger.Error("GetStats: unique visitors", "short_code", stats.ShortCode, "error", err)
+		return
+	}
+
+	stats.UniqueVisitors = total
+	stats.DailyUniqueVisitors = make([]StatsBucket, len(days))
+	for i, day := range days {
+		stats.DailyUniqueVisitors[i] = StatsBucket{Start: day, Count: daily[i]}
+	}
+}
+
+func bucketStep(bucket string) (func(time.Time) time.Time, error) {
+	switch bucket {
+	case "hour":
+		return func(t time.Time) time.Time { return t.Add(time.Hour) }, nil
+	case "day":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }, nil
+	case "week":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }, nil
+	}
+	return nil, fmt.Errorf("%w: unknown bucket %q", ErrInvalidRange, bucket)
+}
+
+// truncateBucket mirrors postgres date_trunc in UTC; weeks start on Monday.
+func truncateBucket(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	switch bucket {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func fillBuckets(series []repository.ClickBucket, from, to time.Time, bucket string, step func(time.Time) time.Time) []StatsBucket {
+	counts := make(map[int64]int64, len(series))
+	for _, b := range series {
+		counts[b.Start.Unix()] = b.Count
+	}
+
+	res := []StatsBucket{}
+	for t := truncateBucket(from, bucket); t.Before(to); t = step(t) {
+		res = append(res, StatsBucket{Start: t, Count: counts[t.Unix()]})
+	}
+	return res
+}
+
+func toStatsCounts(counts []repository.DimensionCount) []StatsCount {
+	res := []StatsCount{}
+	for _, c := range counts {
+		res = append(res, StatsCount{Value: c.Value, Count: c.Count})
+	}
+	return res
+}
+
+// anonymizeIP zeroes the host part of the address: the last octet for IPv4
+// and everything after the /48 prefix for IPv6.
+func anonymizeIP(raw string) string {
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return ""
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// visitorFingerprint derives a stable pseudonymous visitor ID. Raw addresses
+// never leave the process; only the salted hash is stored in the sketches.
+func visitorFingerprint(salt, ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(salt + "\x00" + ip + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+func referrerDomain(referrer string) string {
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+)
+
+const counterIncrTimeout = time.Second
+
+type ClickCountRepository interface {
+	IncrementClickCounts(counts map[string]int64) error
+}
+
+// CounterBuffer accumulates clicks per short code between flushes.
+type CounterBuffer interface {
+	Incr(ctx context.Context, shortCode string) error
+	// Drain atomically returns the buffered counts and resets the buffer.
+	Drain(ctx context.Context) (map[string]int64, error)
+	// Restore adds counts that could not be flushed back to the buffer.
+	Restore(ctx context.Context, counts map[string]int64) error
+}
+
+// ClickCounter buffers clicks and periodically adds them to the persisted
+// per-link totals in one batched update, so redirects never wait on a
+// database write.
+type ClickCounter struct {
+	buffer   CounterBuffer
+	repo     ClickCountRepository
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewClickCounter(buffer CounterBuffer, repo ClickCountRepository, interval time.Duration, logger *slog.Logger) *ClickCounter {
+	return &ClickCounter{
+		buffer:   buffer,
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (c *ClickCounter) Add(shortCode string) {
+	ctx, cancel := context.WithTimeout(context.Background(), counterIncrTimeout)
+	defer cancel()
+
+	if err := c.buffer.Incr(ctx, shortCode); err != nil {
+		metrics.ClicksDropped.Inc()
+		c.logger.Error("ClickCounter: incr", "short_code", shortCode, "error", err)
+	}
+}
+
+// Run flushes the buffer every interval until ctx is done. The final flush
+// is left to the caller, after the HTTP server has stopped accepting clicks.
+func (c *ClickCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil {
+				c.logger.Error("ClickCounter: flush", "error", err)
+			}
+		}
+	}
+}
+
+func (c *ClickCounter) Flush(ctx context.Context) error {
+	counts, err := c.buffer.Drain(ctx)
+	if err != nil {
+		metrics.ClickFlushes.WithLabelValues("error").Inc()
+		return err
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	if err := c.repo.IncrementClickCounts(counts); err != nil {
+		metrics.ClickFlushes.WithLabelValues("error").Inc()
+		// Put the counts back so the next flush retries them.
+		if restoreErr := c.buffer.Restore(context.WithoutCancel(ctx), counts); restoreErr != nil {
+			c.logger.Error("ClickCounter: restore", "codes", len(counts), "error", restoreErr)
+		}
+		return err
+	}
+
+	metrics.ClickFlushes.WithLabelValues("ok").Inc()
+	return nil
+}
+
+// MemoryCounterBuffer keeps counts in process. Counts buffered by an instance
+// that crashes before flushing are lost.
+type MemoryCounterBuffer struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func NewMemoryCounterBuffer() *MemoryCounterBuffer {
+	return &MemoryCounterBuffer{counts: make(map[string]int64)}
+}
+
+func (b *MemoryCounterBuffer) Incr(_ context.Context, shortCode string) error {
+	b.mu.Lock()
+	b.counts[shortCode]++
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *MemoryCounterBuffer) Drain(_ context.Context) (map[string]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counts := b.counts
+	b.counts = make(map[string]int64)
+	return counts, nil
+}
+
+func (b *MemoryCounterBuffer) Restore(_ context.Context, counts map[string]int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for shortCode, count := range counts {
+		b.counts[shortCode] += count
+	}
+	return nil
+}
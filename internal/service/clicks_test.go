@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type fakeClickCountRepository struct {
+	err    error
+	counts map[string]int64
+}
+
+func (f *fakeClickCountRepository) IncrementClickCounts(counts map[string]int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	for shortCode, count := range counts {
+		f.counts[shortCode] += count
+	}
+	return nil
+}
+
+func TestClickCounter_Flush(t *testing.T) {
+	repo := &fakeClickCountRepository{counts: make(map[string]int64)}
+	buffer := NewMemoryCounterBuffer()
+	counter := NewClickCounter(buffer, repo, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	counter.Add("abc123")
+	counter.Add("abc123")
+	counter.Add("xyz789")
+
+	repo.err = errors.New("database unavailable")
+	if err := counter.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	counter.Add("abc123")
+
+	repo.err = nil
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if repo.counts["abc123"] != 3 || repo.counts["xyz789"] != 1 {
+		t.Errorf("counts after retry = %v, want abc123:3 xyz789:1", repo.counts)
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if repo.counts["abc123"] != 3 {
+		t.Errorf("flushed counts twice: %v", repo.counts)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+)
+
+const codeAnnouncementsRetry = 5 * time.Second
+
+// CodeFilter is a probabilistic set of existing short codes. Test may return
+// true for codes that do not exist, never false for codes that were added.
+type CodeFilter interface {
+	Add(shortCode string)
+	Test(shortCode string) bool
+	Rebuild(fill func(add func(string)) error) error
+}
+
+// CodeAnnouncements broadcasts newly created short codes to every instance.
+// Subscribe calls reset whenever the subscription is (re)established, since
+// codes published while it was down are lost.
+type CodeAnnouncements interface {
+	Publish(ctx context.Context, shortCode string) error
+	Subscribe(ctx context.Context, handle func(shortCode string), reset func()) error
+}
+
+// WithCodeFilter rejects short codes missing from filter without querying
+// postgres. Codes created on any instance are added through announcements.
+// The filter is only consulted while subscribed to them and after a rebuild
+// that started after the subscription, so no new code can be missing from
+// it. Deleted codes stay in the filter until the next rebuild and are
+// answered by the negative cache or postgres meanwhile.
+func (s *URLService) WithCodeFilter(filter CodeFilter, announcements CodeAnnouncements) *URLService {
+	s.codes = filter
+	s.newCodes = announcements
+	return s
+}
+
+// CodeFilterReady reports whether unknown codes are currently rejected by
+// the code filter.
+func (s *URLService) CodeFilterReady() bool {
+	return s.codes != nil && s.codesReady.Load()
+}
+
+// RebuildCodeFilter refills the code filter from postgres.
+func (s *URLService) RebuildCodeFilter() error {
+	if s.codes == nil {
+		return nil
+	}
+
+	s.codesMu.Lock()
+	subscribed, epoch := s.codesSubscribed, s.codesEpoch
+	s.codesMu.Unlock()
+
+	start := time.Now()
+	codes := 0
+	err := s.codes.Rebuild(func(add func(string)) error {
+		return s.postgres.ForEachShortCode(func(shortCode string) {
+			add(shortCode)
+			codes++
+		})
+	})
+	if err != nil {
+		metrics.CodeFilterRebuilds.WithLabelValues("error").Inc()
+		return err
+	}
+
+	metrics.CodeFilterRebuilds.WithLabelValues("ok").Inc()
+
+	s.codesMu.Lock()
+	if subscribed && epoch == s.codesEpoch {
+		s.codesReady.Store(true)
+	}
+	s.codesMu.Unlock()
+
+	s.logger.Info("code filter rebuilt", "codes", codes, "duration", time.Since(start))
+	return nil
+}
+
+// RunCodeFilter follows code announcements and rebuilds the code filter
+// every interval to forget deleted codes, and every time the announcements
+// subscription is re-established to pick up codes announced meanwhile.
+func (s *URLService) RunCodeFilter(ctx context.Context, interval time.Duration) {
+	rebuild := make(chan struct{}, 1)
+	go s.followCodeAnnouncements(ctx, func() {
+		select {
+		case rebuild <- struct{}{}:
+		default:
+		}
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rebuild:
+		case <-ticker.C:
+		}
+
+		if err := s.RebuildCodeFilter(); err != nil {
+			s.logger.Error("RunCodeFilter: rebuild", "error", err)
+		}
+	}
+}
+
+func (s *URLService) followCodeAnnouncements(ctx context.Context, rebuild func()) {
+	for ctx.Err() == nil {
+		err := s.newCodes.Subscribe(ctx, s.codes.Add, func() {
+			// Codes may have been announced while the subscription was down:
+			// stop trusting the filter until it is rebuilt.
+			s.setCodesSubscribed(true)
+			rebuild()
+		})
+		s.setCodesSubscribed(false)
+		if err != nil {
+			s.logger.Error("RunCodeFilter: subscribe to code announcements", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(codeAnnouncementsRetry):
+		}
+	}
+}
+
+// setCodesSubscribed records a change of the announcements subscription.
+// Either way the filter may lack codes and is not used until a rebuild that
+// starts after the change completes.
+func (s *URLService) setCodesSubscribed(subscribed bool) {
+	s.codesMu.Lock()
+	defer s.codesMu.Unlock()
+
+	s.codesSubscribed = subscribed
+	s.codesEpoch++
+	s.codesReady.Store(false)
+}
+
+// announceCode adds a new code to the filter of every instance.
+func (s *URLService) announceCode(ctx context.Context, shortCode string) {
+	if s.codes == nil {
+		return
+	}
+
+	s.codes.Add(shortCode)
+	if err := s.newCodes.Publish(ctx, shortCode); err != nil {
+		// Other instances answer the code with 404 until their filter is
+		// rebuilt or they resubscribe.
+		s.logger.Error("ShortenURL: announce code", "short_code", shortCode, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/bloom"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+// fakeAnnouncements delivers published codes to every subscriber
+// synchronously, like a Pub/Sub channel shared by all instances.
+type fakeAnnouncements struct {
+	mu          sync.Mutex
+	subscribers []func(string)
+}
+
+func (f *fakeAnnouncements) Publish(ctx context.Context, shortCode string) error {
+	f.mu.Lock()
+	subscribers := append([]func(string){}, f.subscribers...)
+	f.mu.Unlock()
+
+	for _, handle := range subscribers {
+		handle(shortCode)
+	}
+	return nil
+}
+
+func (f *fakeAnnouncements) Subscribe(ctx context.Context, handle func(string), reset func()) error {
+	f.mu.Lock()
+	f.subscribers = append(f.subscribers, handle)
+	f.mu.Unlock()
+
+	reset()
+	<-ctx.Done()
+	return nil
+}
+
+func waitCodeFilterReady(t *testing.T, services ...*URLService) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, s := range services {
+		for !s.CodeFilterReady() {
+			if time.Now().After(deadline) {
+				t.Fatal("code filter not ready")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestService_CodeFilterSharesNewCodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := &fakeURLRepository{urls: map[string]*repository.URL{}}
+	announcements := &fakeAnnouncements{}
+
+	// The cache is down, so instance b can only learn about codes created
+	// on instance a through the announcements.
+	newInstance := func() *URLService {
+		cache := &fakeCache{values: map[string]string{}, err: errors.New("connection refused")}
+		s := newTestURLService(repo, cache).WithCodeFilter(bloom.New(1000, 0.001), announcements)
+		go s.RunCodeFilter(ctx, time.Hour)
+		return s
+	}
+	a, b := newInstance(), newInstance()
+	waitCodeFilterReady(t, a, b)
+
+	created, err := a.ShortenURL("https://example.com")
+	if err != nil {
+		t.Fatalf("ShortenURL: %v", err)
+	}
+
+	url, err := b.GetOriginalURL(created.ShortCode)
+	if err != nil {
+		t.Fatalf("GetOriginalURL on the other instance: %v", err)
+	}
+	if url.OriginalURL != "https://example.com" {
+		t.Errorf("OriginalURL = %q", url.OriginalURL)
+	}
+
+	queries := repo.queries.Load()
+	if _, err := b.GetOriginalURL("zzzzzz"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetOriginalURL(unknown) error = %v, want ErrNotFound", err)
+	}
+	if repo.queries.Load() != queries {
+		t.Error("unknown code reached postgres with the code filter ready")
+	}
+}
+
+func TestService_CodeFilterUnusedUntilSubscribed(t *testing.T) {
+	repo := &fakeURLRepository{urls: map[string]*repository.URL{}}
+	s := newTestURLService(repo, &fakeCache{values: map[string]string{}}).
+		WithCodeFilter(bloom.New(1000, 0.001), &fakeAnnouncements{})
+
+	// A rebuild alone does not make the filter authoritative: codes created
+	// elsewhere would be missed without the announcements.
+	if err := s.RebuildCodeFilter(); err != nil {
+		t.Fatalf("RebuildCodeFilter: %v", err)
+	}
+	if s.CodeFilterReady() {
+		t.Fatal("code filter ready without an announcements subscription")
+	}
+
+	if _, err := s.GetOriginalURL("zzzzzz"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetOriginalURL(unknown) error = %v, want ErrNotFound", err)
+	}
+	if repo.queries.Load() != 1 {
+		t.Errorf("postgres queries = %d, want 1", repo.queries.Load())
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+const maxLeaderboardLimit = 100
+
+var ErrLeaderboardDisabled = errors.New("leaderboard is disabled")
+
+type Leaderboard interface {
+	Record(ctx context.Context, shortCode string, at time.Time) error
+	Top(ctx context.Context, w repository.LeaderboardWindow, at time.Time, limit int) ([]repository.LeaderboardEntry, error)
+	Trending(ctx context.Context, w repository.LeaderboardWindow, at time.Time) ([]repository.LeaderboardEntry, error)
+}
+
+type LinkRank struct {
+	ShortCode      string
+	OriginalURL    string
+	Clicks         int64
+	PreviousClicks int64
+	// Change is Clicks minus PreviousClicks; only set for trending links.
+	Change int64
+}
+
+// WithLeaderboard tracks clicks per time bucket for the top and trending
+// endpoints. Bot clicks are not counted.
+func (s *AnalyticsService) WithLeaderboard(leaderboard Leaderboard) *AnalyticsService {
+	s.leaderboard = leaderboard
+	return s
+}
+
+// GetTopLinks returns the most clicked links over the window: hour, day or
+// week.
+func (s *AnalyticsService) GetTopLinks(window string, limit int) ([]LinkRank, error) {
+	w, err := s.leaderboardWindow(window, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	entries, err := s.leaderboard.Top(ctx, w, time.Now(), limit)
+	if err != nil {
+		s.logger.Error("GetTopLinks:", "window", window, "error", err)
+		return nil, err
+	}
+
+	return s.rankLinks(entries)
+}
+
+// GetTrendingLinks returns the links whose clicks grew the most compared to
+// the previous window of the same length.
+func (s *AnalyticsService) GetTrendingLinks(window string, limit int) ([]LinkRank, error) {
+	w, err := s.leaderboardWindow(window, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	entries, err := s.leaderboard.Trending(ctx, w, time.Now())
+	if err != nil {
+		s.logger.Error("GetTrendingLinks:", "window", window, "error", err)
+		return nil, err
+	}
+
+	var growing []repository.LeaderboardEntry
+	for _, e := range entries {
+		if e.Clicks > e.PreviousClicks {
+			growing = append(growing, e)
+		}
+	}
+
+	sort.SliceStable(growing, func(i, j int) bool {
+		di := growing[i].Clicks - growing[i].PreviousClicks
+		dj := growing[j].Clicks - growing[j].PreviousClicks
+		if di != dj {
+			return di > dj
+		}
+		return growing[i].Clicks > growing[j].Clicks
+	})
+
+	if len(growing) > limit {
+		growing = growing[:limit]
+	}
+
+	links, err := s.rankLinks(growing)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		links[i].Change = links[i].Clicks - links[i].PreviousClicks
+	}
+	return links, nil
+}
+
+func (s *AnalyticsService) leaderboardWindow(window string, limit int) (repository.LeaderboardWindow, error) {
+	if s.leaderboard == nil {
+		return repository.LeaderboardWindow{}, ErrLeaderboardDisabled
+	}
+
+	w, ok := repository.LeaderboardWindows[window]
+	if !ok {
+		return repository.LeaderboardWindow{}, fmt.Errorf("%w: unknown window %q", ErrInvalidRange, window)
+	}
+
+	if limit < 1 || limit > maxLeaderboardLimit {
+		return repository.LeaderboardWindow{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRange, maxLeaderboardLimit)
+	}
+
+	return w, nil
+}
+
+// rankLinks attaches destinations to leaderboard entries, keeping their
+// order. Links deleted since they were clicked are dropped.
+func (s *AnalyticsService) rankLinks(entries []repository.LeaderboardEntry) ([]LinkRank, error) {
+	links := []LinkRank{}
+	if len(entries) == 0 {
+		return links, nil
+	}
+
+	codes := make([]string, len(entries))
+	for i, e := range entries {
+		codes[i] = e.ShortCode
+	}
+
+	urls, err := s.urls.GetURLsByShortCodes(codes)
+	if err != nil {
+		s.logger.Error("rankLinks:", "error", err)
+		return nil, err
+	}
+
+	destinations := make(map[string]string, len(urls))
+	for _, url := range urls {
+		destinations[url.ShortCode] = url.OriginalURL
+	}
+
+	for _, e := range entries {
+		destination, ok := destinations[e.ShortCode]
+		if !ok {
+			continue
+		}
+		links = append(links, LinkRank{
+			ShortCode:      e.ShortCode,
+			OriginalURL:    destination,
+			Clicks:         e.Clicks,
+			PreviousClicks: e.PreviousClicks,
+		})
+	}
+	return links, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type fakeLeaderboard struct {
+	top      []repository.LeaderboardEntry
+	trending []repository.LeaderboardEntry
+	window   repository.LeaderboardWindow
+	limit    int
+}
+
+func (l *fakeLeaderboard) Record(ctx context.Context, shortCode string, at time.Time) error {
+	return nil
+}
+
+func (l *fakeLeaderboard) Top(ctx context.Context, w repository.LeaderboardWindow, at time.Time, limit int) ([]repository.LeaderboardEntry, error) {
+	l.window, l.limit = w, limit
+	return l.top, nil
+}
+
+func (l *fakeLeaderboard) Trending(ctx context.Context, w repository.LeaderboardWindow, at time.Time) ([]repository.LeaderboardEntry, error) {
+	l.window = w
+	return l.trending, nil
+}
+
+// fakeURLLookup knows the links in urls; other short codes are treated as
+// deleted.
+type fakeURLLookup struct {
+	urls map[string]string
+}
+
+func (f *fakeURLLookup) GetURLByShortCode(shortCode string) (*repository.URL, error) {
+	destination, ok := f.urls[shortCode]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &repository.URL{ShortCode: shortCode, OriginalURL: destination}, nil
+}
+
+func (f *fakeURLLookup) GetURLsByShortCodes(shortCodes []string) ([]repository.URL, error) {
+	var res []repository.URL
+	for _, code := range shortCodes {
+		if destination, ok := f.urls[code]; ok {
+			res = append(res, repository.URL{ShortCode: code, OriginalURL: destination})
+		}
+	}
+	return res, nil
+}
+
+func newLeaderboardService(leaderboard Leaderboard, urls map[string]string) *AnalyticsService {
+	lookup := &fakeURLLookup{urls: urls}
+	s := NewAnalyticsService(lookup, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if leaderboard != nil {
+		s.WithLeaderboard(leaderboard)
+	}
+	return s
+}
+
+func shortCodes(links []LinkRank) []string {
+	codes := make([]string, len(links))
+	for i, l := range links {
+		codes[i] = l.ShortCode
+	}
+	return codes
+}
+
+func TestGetTrendingLinks_OrdersByGrowth(t *testing.T) {
+	leaderboard := &fakeLeaderboard{trending: []repository.LeaderboardEntry{
+		{ShortCode: "flat", Clicks: 10, PreviousClicks: 10},
+		{ShortCode: "falling", Clicks: 2, PreviousClicks: 8},
+		{ShortCode: "small", Clicks: 3, PreviousClicks: 1},
+		{ShortCode: "tie-low", Clicks: 5, PreviousClicks: 0},
+		{ShortCode: "big", Clicks: 20, PreviousClicks: 5},
+		{ShortCode: "tie-high", Clicks: 9, PreviousClicks: 4},
+		{ShortCode: "deleted", Clicks: 50, PreviousClicks: 0},
+	}}
+	urls := map[string]string{
+		"flat": "https://a.example", "falling": "https://b.example", "small": "https://c.example",
+		"tie-low": "https://d.example", "big": "https://e.example", "tie-high": "https://f.example",
+	}
+	s := newLeaderboardService(leaderboard, urls)
+
+	links, err := s.GetTrendingLinks("day", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Links that did not grow are dropped, equal growth is ordered by
+	// current clicks, and deleted links are skipped.
+	want := []string{"big", "tie-high", "tie-low", "small"}
+	if got := shortCodes(links); !slices.Equal(got, want) {
+		t.Fatalf("trending = %v, want %v", got, want)
+	}
+	if links[0].Change != 15 || links[0].OriginalURL != "https://e.example" {
+		t.Errorf("first link = %+v, want change 15 to https://e.example", links[0])
+	}
+	if leaderboard.window != repository.LeaderboardWindows["day"] {
+		t.Errorf("queried window %+v, want the day window", leaderboard.window)
+	}
+}
+
+func TestGetTrendingLinks_AppliesLimitBeforeLookup(t *testing.T) {
+	leaderboard := &fakeLeaderboard{trending: []repository.LeaderboardEntry{
+		{ShortCode: "a", Clicks: 4, PreviousClicks: 0},
+		{ShortCode: "b", Clicks: 3, PreviousClicks: 0},
+		{ShortCode: "c", Clicks: 2, PreviousClicks: 0},
+	}}
+	s := newLeaderboardService(leaderboard, map[string]string{"a": "https://a.example", "b": "https://b.example", "c": "https://c.example"})
+
+	links, err := s.GetTrendingLinks("hour", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := shortCodes(links); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("trending = %v, want [a b]", got)
+	}
+}
+
+func TestGetTopLinks_KeepsLeaderboardOrder(t *testing.T) {
+	leaderboard := &fakeLeaderboard{top: []repository.LeaderboardEntry{
+		{ShortCode: "b", Clicks: 9},
+		{ShortCode: "a", Clicks: 4},
+	}}
+	s := newLeaderboardService(leaderboard, map[string]string{"a": "https://a.example", "b": "https://b.example"})
+
+	links, err := s.GetTopLinks("week", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := shortCodes(links); !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf("top = %v, want [b a]", got)
+	}
+	if leaderboard.limit != 5 {
+		t.Errorf("limit passed to leaderboard = %d, want 5", leaderboard.limit)
+	}
+}
+
+func TestLeaderboardWindow_Validation(t *testing.T) {
+	s := newLeaderboardService(&fakeLeaderboard{}, nil)
+
+	tests := []struct {
+		window string
+		limit  int
+		want   error
+	}{
+		{window: "day", limit: 1},
+		{window: "week", limit: maxLeaderboardLimit},
+		{window: "month", limit: 10, want: ErrInvalidRange},
+		{window: "day", limit: 0, want: ErrInvalidRange},
+		{window: "day", limit: maxLeaderboardLimit + 1, want: ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		_, err := s.leaderboardWindow(tt.window, tt.limit)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("leaderboardWindow(%q, %d) error = %v, want %v", tt.window, tt.limit, err, tt.want)
+		}
+	}
+
+	disabled := newLeaderboardService(nil, nil)
+	if _, err := disabled.GetTopLinks("day", 10); !errors.Is(err, ErrLeaderboardDisabled) {
+		t.Errorf("expected ErrLeaderboardDisabled, got %v", err)
+	}
+}
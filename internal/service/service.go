@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
 	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/resolver"
+	"github.com/Vadim-Makhnev/url-shortener/internal/scanner"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -14,78 +21,276 @@ var (
 )
 
 type URL struct {
-	ShortCode   string
-	OriginalURL string
-	CreatedAt   time.Time
+	ShortCode     string
+	OriginalURL   string
+	RiskScore     int
+	Flagged       bool
+	FinalURL      string
+	RedirectChain []string
+	LastStatus    int
+	LastCheckedAt time.Time
+	CheckFailures int
+	Disabled      bool
+	ClickCount    int64
+	CreatedAt     time.Time
 }
 
 type RepositoryPostgres interface {
-	CreateURL(shortCode, originalURL string) (*repository.URL, error)
-	GetURLByShortCode(shortCode string) (string, error)
+	CreateURL(url *repository.URL) (*repository.URL, error)
+	GetURLByShortCode(shortCode string) (*repository.URL, error)
 	GetAllURLS() ([]repository.URL, error)
+	GetBrokenURLs() ([]repository.URL, error)
+	GetURLsByPopularity(order string, after *repository.URL, limit int) ([]repository.URL, error)
+	DeleteURL(shortCode string) error
+	ForEachShortCode(fn func(shortCode string)) error
 }
 
-type RepositoryRedis interface {
+// Cache holds destinations of plain links by short code, and short codes
+// known not to exist as missing entries. Get reports misses with
+// repository.ErrCacheMiss and missing entries with repository.ErrNotFound.
+type Cache interface {
 	Set(ctx context.Context, shortCode, originalURL string) error
+	Fill(ctx context.Context, shortCode, originalURL string) error
 	Get(ctx context.Context, shortCode string) (string, error)
+	SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error
+	Delete(ctx context.Context, shortCode string) error
+}
+
+type Scanner interface {
+	ScanChain(chain []string) scanner.Result
+	Flagged(score int) bool
+}
+
+type Resolver interface {
+	Resolve(ctx context.Context, rawURL string) (*resolver.Result, error)
 }
 
 type URLService struct {
 	postgres RepositoryPostgres
 	logger   *slog.Logger
-	redis    RepositoryRedis
+	cache    Cache
+	scanner  Scanner
+	resolver Resolver
+
+	missingTTL time.Duration
+	codes      CodeFilter
+	newCodes   CodeAnnouncements
+	codesReady atomic.Bool
+	// codesMu guards the announcements subscription state; codesEpoch
+	// changes with every subscription change.
+	codesMu         sync.Mutex
+	codesSubscribed bool
+	codesEpoch      uint64
+	lookups         singleflight.Group
+	warmup          WarmupOptions
+	warming         atomic.Bool
 }
 
-func NewService(repo RepositoryPostgres, redis RepositoryRedis, logger *slog.Logger) *URLService {
+// NewService creates a URLService. resolver may be nil, in which case
+// destinations are stored without following their redirects.
+func NewService(repo RepositoryPostgres, cache Cache, scanner Scanner, resolver Resolver, logger *slog.Logger) *URLService {
 	return &URLService{
 		postgres: repo,
-		redis:    redis,
+		cache:    cache,
+		scanner:  scanner,
+		resolver: resolver,
 		logger:   logger,
 	}
 }
 
+// WithNegativeCache caches unknown short codes for ttl so repeated lookups
+// of the same code are answered by the cache.
+func (s *URLService) WithNegativeCache(ttl time.Duration) *URLService {
+	s.missingTTL = ttl
+	return s
+}
+
 func (s *URLService) ShortenURL(originalURL string) (*URL, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
 	shortCode := generateShortCode()
 
-	url, err := s.postgres.CreateURL(shortCode, originalURL)
+	chain, err := s.resolveChain(originalURL)
 	if err != nil {
-		s.logger.Error("ShortenURL:", "error", err)
 		return nil, err
 	}
 
-	err = s.redis.Set(ctx, shortCode, originalURL)
+	scan := s.scanner.ScanChain(chain)
+	if len(scan.Reasons) > 0 {
+		s.logger.Warn("ShortenURL: suspicious destination", "original_url", originalURL, "risk_score", scan.Score, "reasons", scan.Reasons)
+	}
+
+	newURL := &repository.URL{
+		ShortCode:   shortCode,
+		OriginalURL: originalURL,
+		RiskScore:   scan.Score,
+	}
+	if len(chain) > 1 {
+		newURL.FinalURL = chain[len(chain)-1]
+		newURL.RedirectChain = chain
+	}
+
+	url, err := s.postgres.CreateURL(newURL)
 	if err != nil {
+		s.logger.Error("ShortenURL:", "error", err)
 		return nil, err
 	}
 
-	domainURL := &URL{
-		ShortCode:   url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		CreatedAt:   url.CreatedAt,
+	domainURL := s.toDomain(url)
+
+	s.announceCode(ctx, shortCode)
+
+	// The cache only holds plain destinations, so flagged links are always
+	// read from postgres where the risk score is available. Disabled links are
+	// evicted by the health checker for the same reason.
+	// The link is committed at this point, so cache failures are only logged:
+	// failing the request would make clients retry and create duplicates.
+	if !domainURL.Flagged {
+		if err := s.cache.Set(ctx, shortCode, originalURL); err != nil {
+			s.logCacheError("ShortenURL: cache", shortCode, err)
+		}
+	} else if s.missingTTL > 0 {
+		if err := s.cache.Delete(ctx, shortCode); err != nil {
+			s.logCacheError("ShortenURL: clear missing entry", shortCode, err)
+		}
 	}
 
 	return domainURL, nil
 }
 
-func (s *URLService) GetOriginalURL(shortCode string) (string, error) {
+// resolveChain follows the redirects of originalURL when a resolver is
+// configured. Unreachable destinations are not an error; chains that loop or
+// lead back to this service are.
+func (s *URLService) resolveChain(originalURL string) ([]string, error) {
+	if s.resolver == nil {
+		return []string{originalURL}, nil
+	}
+
+	res, err := s.resolver.Resolve(context.Background(), originalURL)
+	if err != nil {
+		if errors.Is(err, resolver.ErrRedirectLoop) || errors.Is(err, resolver.ErrSelfRedirect) {
+			s.logger.Warn("ShortenURL: rejected redirect chain", "original_url", originalURL, "error", err)
+			return nil, err
+		}
+		s.logger.Warn("ShortenURL: resolve destination", "original_url", originalURL, "error", err)
+		return []string{originalURL}, nil
+	}
+
+	return res.Chain, nil
+}
+
+func (s *URLService) GetOriginalURL(shortCode string) (*URL, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	val, err := s.redis.Get(ctx, shortCode)
-	if err == nil {
-		return val, nil
+	val, err := s.cache.Get(ctx, shortCode)
+	switch {
+	case err == nil:
+		metrics.CacheRequests.WithLabelValues("hit").Inc()
+		return &URL{ShortCode: shortCode, OriginalURL: val}, nil
+	case errors.Is(err, repository.ErrNotFound):
+		metrics.CacheRequests.WithLabelValues("negative_hit").Inc()
+		return nil, repository.ErrNotFound
+	case errors.Is(err, repository.ErrCacheMiss):
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+	case errors.Is(err, repository.ErrCacheUnavailable):
+		metrics.CacheRequests.WithLabelValues("unavailable").Inc()
+	default:
+		metrics.CacheRequests.WithLabelValues("error").Inc()
+		s.logger.Warn("GetOriginalURL: cache", "short_code", shortCode, "error", err)
+	}
+
+	if s.codes != nil && s.codesReady.Load() && !s.codes.Test(shortCode) {
+		metrics.CodeFilterRejections.Inc()
+		return nil, repository.ErrNotFound
+	}
+
+	// Concurrent misses for the same code share one postgres query and one
+	// cache fill. Each caller gets its own copy of the result.
+	v, err, shared := s.lookups.Do(shortCode, func() (any, error) {
+		return s.loadURL(shortCode)
+	})
+	if shared {
+		metrics.CacheLookupsCoalesced.Inc()
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	url := *v.(*URL)
+	return &url, nil
+}
+
+// loadURL reads a link from postgres and writes the result back to the
+// cache: the destination for plain links, a missing entry for unknown codes.
+func (s *URLService) loadURL(shortCode string) (*URL, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
 
 	url, err := s.postgres.GetURLByShortCode(shortCode)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.cacheMissing(ctx, shortCode)
+			return nil, err
+		}
 		s.logger.Error("GetOriginalURL:", "error", err)
-		return "", err
+		return nil, err
 	}
 
-	return url, nil
+	domainURL := s.toDomain(url)
+
+	if !domainURL.Flagged && !domainURL.Disabled {
+		if err := s.cache.Fill(ctx, shortCode, domainURL.OriginalURL); err != nil {
+			s.logCacheError("GetOriginalURL: fill cache", shortCode, err)
+		}
+	}
+
+	return domainURL, nil
+}
+
+// DeleteURL removes a link and replaces its cache entry with a missing one,
+// so instances sharing the cache stop redirecting immediately. The missing
+// entry is written even without negative caching: it keeps concurrent
+// read-through fills from caching the link again.
+func (s *URLService) DeleteURL(shortCode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err := s.postgres.DeleteURL(shortCode); err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			s.logger.Error("DeleteURL:", "error", err)
+		}
+		return err
+	}
+
+	if err := s.cache.Delete(ctx, shortCode); err != nil {
+		s.logCacheError("DeleteURL: cache", shortCode, err)
+	}
+	if err := s.cache.SetMissing(ctx, shortCode, max(s.missingTTL, repository.DeletedCacheTTL)); err != nil {
+		s.logCacheError("DeleteURL: cache missing code", shortCode, err)
+	}
+
+	return nil
+}
+
+func (s *URLService) cacheMissing(ctx context.Context, shortCode string) {
+	if s.missingTTL <= 0 {
+		return
+	}
+	if err := s.cache.SetMissing(ctx, shortCode, s.missingTTL); err != nil {
+		s.logCacheError("cache missing code", shortCode, err)
+	}
+}
+
+// logCacheError logs a failed cache call. Calls rejected while the cache is
+// known to be unavailable are not logged one by one; the breaker logs its
+// state changes instead.
+func (s *URLService) logCacheError(msg, shortCode string, err error) {
+	if errors.Is(err, repository.ErrCacheUnavailable) {
+		return
+	}
+	s.logger.Warn(msg, "short_code", shortCode, "error", err)
 }
 
 func (s *URLService) GetAllURLS() ([]URL, error) {
@@ -98,16 +303,45 @@ func (s *URLService) GetAllURLS() ([]URL, error) {
 	var res []URL
 
 	for _, url := range urls {
-		res = append(res, URL{
-			ShortCode:   url.ShortCode,
-			OriginalURL: url.OriginalURL,
-			CreatedAt:   url.CreatedAt,
-		})
+		res = append(res, *s.toDomain(&url))
+	}
+
+	return res, nil
+}
+
+func (s *URLService) GetBrokenURLs() ([]URL, error) {
+	urls, err := s.postgres.GetBrokenURLs()
+	if err != nil {
+		s.logger.Error("GetBrokenURLs:", "error", err)
+		return nil, err
+	}
+
+	var res []URL
+
+	for _, url := range urls {
+		res = append(res, *s.toDomain(&url))
 	}
 
 	return res, nil
 }
 
+func (s *URLService) toDomain(url *repository.URL) *URL {
+	return &URL{
+		ShortCode:     url.ShortCode,
+		OriginalURL:   url.OriginalURL,
+		RiskScore:     url.RiskScore,
+		Flagged:       s.scanner.Flagged(url.RiskScore),
+		FinalURL:      url.FinalURL,
+		RedirectChain: url.RedirectChain,
+		LastStatus:    url.LastStatus,
+		LastCheckedAt: url.LastCheckedAt,
+		CheckFailures: url.CheckFailures,
+		Disabled:      url.Disabled,
+		ClickCount:    url.ClickCount,
+		CreatedAt:     url.CreatedAt,
+	}
+}
+
 func generateShortCode() string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	const length = 6
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+	"github.com/Vadim-Makhnev/url-shortener/internal/scanner"
+)
+
+type fakeURLRepository struct {
+	RepositoryPostgres
+	mu      sync.Mutex
+	urls    map[string]*repository.URL
+	queries atomic.Int32
+	release chan struct{}
+}
+
+func (f *fakeURLRepository) GetURLByShortCode(shortCode string) (*repository.URL, error) {
+	f.queries.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	url, ok := f.urls[shortCode]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return url, nil
+}
+
+func (f *fakeURLRepository) CreateURL(url *repository.URL) (*repository.URL, error) {
+	created := *url
+	created.CreatedAt = time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.urls != nil {
+		f.urls[created.ShortCode] = &created
+	}
+	return &created, nil
+}
+
+func (f *fakeURLRepository) DeleteURL(shortCode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.urls[shortCode]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.urls, shortCode)
+	return nil
+}
+
+func (f *fakeURLRepository) ForEachShortCode(fn func(shortCode string)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for shortCode := range f.urls {
+		fn(shortCode)
+	}
+	return nil
+}
+
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error
+}
+
+func (f *fakeCache) Set(ctx context.Context, shortCode, originalURL string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[shortCode] = originalURL
+	return nil
+}
+
+func (f *fakeCache) Fill(ctx context.Context, shortCode, originalURL string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.values[shortCode]; !ok {
+		f.values[shortCode] = originalURL
+	}
+	return nil
+}
+
+func (f *fakeCache) Get(ctx context.Context, shortCode string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	val, ok := f.values[shortCode]
+	switch {
+	case !ok:
+		return "", repository.ErrCacheMiss
+	case val == "":
+		return "", repository.ErrNotFound
+	}
+	return val, nil
+}
+
+func (f *fakeCache) SetMissing(ctx context.Context, shortCode string, ttl time.Duration) error {
+	return f.Set(ctx, shortCode, "")
+}
+
+func (f *fakeCache) Delete(ctx context.Context, shortCode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.values, shortCode)
+	return nil
+}
+
+func newTestURLService(repo RepositoryPostgres, cache Cache) *URLService {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewService(repo, cache, scanner.New(scanner.DefaultThreshold), nil, logger)
+}
+
+func TestService_GetOriginalURLReadThrough(t *testing.T) {
+	repo := &fakeURLRepository{urls: map[string]*repository.URL{
+		"abc123": {ShortCode: "abc123", OriginalURL: "https://example.com"},
+	}}
+	cache := &fakeCache{values: map[string]string{}}
+	svc := newTestURLService(repo, cache).WithNegativeCache(time.Minute)
+
+	for range 3 {
+		url, err := svc.GetOriginalURL("abc123")
+		if err != nil {
+			t.Fatalf("GetOriginalURL: %v", err)
+		}
+		if url.OriginalURL != "https://example.com" {
+			t.Errorf("OriginalURL = %q", url.OriginalURL)
+		}
+	}
+
+	for range 3 {
+		if _, err := svc.GetOriginalURL("zzzzzz"); err != repository.ErrNotFound {
+			t.Fatalf("GetOriginalURL(unknown) error = %v, want ErrNotFound", err)
+		}
+	}
+
+	if got := repo.queries.Load(); got != 2 {
+		t.Errorf("postgres queries = %d, want 2", got)
+	}
+}
+
+func TestService_GetOriginalURLCoalescesMisses(t *testing.T) {
+	repo := &fakeURLRepository{
+		urls: map[string]*repository.URL{
+			"abc123": {ShortCode: "abc123", OriginalURL: "https://example.com"},
+		},
+		release: make(chan struct{}),
+	}
+	svc := newTestURLService(repo, &fakeCache{values: map[string]string{}})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, err := svc.GetOriginalURL("abc123"); err != nil {
+				t.Errorf("GetOriginalURL: %v", err)
+			}
+		})
+	}
+
+	// Let the callers pile up behind the first query before releasing it.
+	for repo.queries.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	if got := repo.queries.Load(); got != 1 {
+		t.Errorf("postgres queries = %d, want 1", got)
+	}
+}
+
+func TestService_DeleteURLBlocksLateFill(t *testing.T) {
+	repo := &fakeURLRepository{urls: map[string]*repository.URL{
+		"abc123": {ShortCode: "abc123", OriginalURL: "https://example.com"},
+	}}
+	cache := &fakeCache{values: map[string]string{}}
+	// Negative caching is off, yet the delete must still leave a missing
+	// entry behind.
+	svc := newTestURLService(repo, cache)
+
+	if err := svc.DeleteURL("abc123"); err != nil {
+		t.Fatalf("DeleteURL: %v", err)
+	}
+
+	// A lookup that read the link before the delete fills the cache late.
+	if err := cache.Fill(context.Background(), "abc123", "https://example.com"); err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+
+	if _, err := svc.GetOriginalURL("abc123"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetOriginalURL after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestService_ShortenURLIgnoresCacheFailure(t *testing.T) {
+	repo := &fakeURLRepository{urls: map[string]*repository.URL{}}
+	cache := &fakeCache{values: map[string]string{}, err: errors.New("connection refused")}
+	svc := newTestURLService(repo, cache)
+
+	url, err := svc.ShortenURL("https://example.com")
+	if err != nil {
+		t.Fatalf("ShortenURL returned the cache error: %v", err)
+	}
+	if url.ShortCode == "" {
+		t.Error("ShortenURL returned no short code")
+	}
+}
+
+type fakePopularURLs struct {
+	fakeURLRepository
+	popular []repository.URL
+	pages   int
+}
+
+func (f *fakePopularURLs) GetURLsByPopularity(order string, after *repository.URL, limit int) ([]repository.URL, error) {
+	f.pages++
+	start := 0
+	if after != nil {
+		start = slices.IndexFunc(f.popular, func(u repository.URL) bool { return u.ID == after.ID }) + 1
+	}
+	end := min(start+limit, len(f.popular))
+	if start >= end {
+		return nil, nil
+	}
+	return f.popular[start:end], nil
+}
+
+func TestService_CacheWarmup(t *testing.T) {
+	repo := &fakePopularURLs{popular: []repository.URL{
+		{ID: 5, ShortCode: "aaaaaa", OriginalURL: "https://a.example"},
+		{ID: 4, ShortCode: "bbbbbb", OriginalURL: "https://b.example", RiskScore: 100},
+		{ID: 3, ShortCode: "cccccc", OriginalURL: "https://c.example"},
+		{ID: 2, ShortCode: "dddddd", OriginalURL: "https://d.example"},
+		{ID: 1, ShortCode: "eeeeee", OriginalURL: "https://e.example"},
+	}}
+	cache := &fakeCache{values: map[string]string{"cccccc": "https://newer.example"}}
+	svc := newTestURLService(repo, cache).WithWarmup(WarmupOptions{
+		Links:     4,
+		Order:     repository.URLOrderClicks,
+		BatchSize: 3,
+	})
+
+	if err := svc.StartCacheWarmup(context.Background()); err != nil {
+		t.Fatalf("StartCacheWarmup: %v", err)
+	}
+	for svc.warming.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	want := map[string]string{
+		"aaaaaa": "https://a.example",
+		"cccccc": "https://newer.example",
+		"dddddd": "https://d.example",
+	}
+	if len(cache.values) != len(want) {
+		t.Errorf("cached %v, want %v", cache.values, want)
+	}
+	for code, url := range want {
+		if cache.values[code] != url {
+			t.Errorf("cache[%s] = %q, want %q", code, cache.values[code], url)
+		}
+	}
+	if repo.pages != 2 {
+		t.Errorf("pages = %d, want 2", repo.pages)
+	}
+}
+
+func TestService_CacheWarmupDisabled(t *testing.T) {
+	svc := newTestURLService(&fakePopularURLs{}, &fakeCache{values: map[string]string{}})
+	if err := svc.StartCacheWarmup(context.Background()); !errors.Is(err, ErrWarmupDisabled) {
+		t.Errorf("StartCacheWarmup without WithWarmup = %v, want ErrWarmupDisabled", err)
+	}
+
+	svc.WithWarmup(WarmupOptions{Links: 0, BatchSize: 10})
+	if err := svc.StartCacheWarmup(context.Background()); !errors.Is(err, ErrWarmupDisabled) {
+		t.Errorf("StartCacheWarmup with no links = %v, want ErrWarmupDisabled", err)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -36,3 +37,33 @@ func TestService_GenerateShortCode(t *testing.T) {
 		codes[code] = true
 	}
 }
+
+func TestService_AnonymizeIP(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.42":              "203.0.113.0",
+		"2001:db8:85a3:8d3:1319::7": "2001:db8:85a3::",
+		"not-an-ip":                 "",
+	}
+
+	for in, want := range tests {
+		if got := anonymizeIP(in); got != want {
+			t.Errorf("anonymizeIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestService_TruncateBucket(t *testing.T) {
+	ts := time.Date(2026, 10, 15, 13, 45, 0, 0, time.UTC) // Thursday
+
+	tests := map[string]time.Time{
+		"hour": time.Date(2026, 10, 15, 13, 0, 0, 0, time.UTC),
+		"day":  time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		"week": time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+	}
+
+	for bucket, want := range tests {
+		if got := truncateBucket(ts, bucket); !got.Equal(want) {
+			t.Errorf("truncateBucket(%s) = %s, want %s", bucket, got, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+)
+
+// WithAsyncTracking takes the Redis and postgres writes of TrackClick off
+// the redirect path: clicks are queued for RunTracking, so an unavailable or
+// slow Redis cannot delay redirects. Clicks arriving while queueSize clicks
+// are waiting are dropped and counted.
+func (s *AnalyticsService) WithAsyncTracking(queueSize int) *AnalyticsService {
+	s.tracking = make(chan Click, queueSize)
+	return s
+}
+
+// RunTracking records queued clicks with the given number of workers until
+// StopTracking is called and the queue is drained.
+func (s *AnalyticsService) RunTracking(workers int) {
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for click := range s.tracking {
+				metrics.ClickTrackingQueued.Set(float64(len(s.tracking)))
+				s.trackClick(click)
+			}
+		})
+	}
+	wg.Wait()
+	metrics.ClickTrackingQueued.Set(0)
+}
+
+// StopTracking closes the tracking queue; clicks tracked afterwards are
+// dropped. Call it once the HTTP server has stopped serving redirects.
+func (s *AnalyticsService) StopTracking() {
+	s.trackingMu.Lock()
+	defer s.trackingMu.Unlock()
+
+	if s.tracking != nil && !s.trackingStopped {
+		s.trackingStopped = true
+		close(s.tracking)
+	}
+}
+
+func (s *AnalyticsService) enqueueClick(click Click) {
+	s.trackingMu.RLock()
+	defer s.trackingMu.RUnlock()
+
+	if s.trackingStopped {
+		metrics.ClickTrackingDropped.Inc()
+		return
+	}
+
+	select {
+	case s.tracking <- click:
+	default:
+		metrics.ClickTrackingDropped.Inc()
+	}
+}
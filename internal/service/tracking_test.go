@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+// blockingSink stalls every write until release is closed, like a click
+// sink behind an unreachable Redis.
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	written []string
+}
+
+func (s *blockingSink) Record(ctx context.Context, click *repository.Click) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written = append(s.written, click.ShortCode)
+	return nil
+}
+
+type countingBuffer struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (b *countingBuffer) Add(shortCode string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.counts[shortCode]++
+}
+
+func TestAnalyticsService_AsyncTrackingDoesNotBlock(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	counter := &countingBuffer{counts: map[string]int{}}
+	s := NewAnalyticsService(nil, nil, sink, counter, slog.New(slog.NewTextHandler(io.Discard, nil))).
+		WithAsyncTracking(2)
+
+	done := make(chan struct{})
+	go func() {
+		s.RunTracking(1)
+		close(done)
+	}()
+
+	start := time.Now()
+	for range 10 {
+		s.TrackClick(Click{ShortCode: "abc123", ClickedAt: time.Now()})
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("TrackClick blocked for %v with the sink stalled", elapsed)
+	}
+
+	close(sink.release)
+	s.StopTracking()
+	<-done
+
+	// One click is held by the worker and two fit in the queue; the rest
+	// are dropped rather than delaying redirects.
+	if len(sink.written) < 1 || len(sink.written) > 3 {
+		t.Errorf("written %d clicks, want between 1 and 3", len(sink.written))
+	}
+	if counter.counts["abc123"] != len(sink.written) {
+		t.Errorf("counted %d clicks, wrote %d", counter.counts["abc123"], len(sink.written))
+	}
+
+	// Clicks after the queue was stopped are dropped, not sent on the
+	// closed channel.
+	s.TrackClick(Click{ShortCode: "abc123"})
+}
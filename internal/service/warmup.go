@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+var (
+	ErrWarmupRunning  = errors.New("cache warm-up already running")
+	ErrWarmupDisabled = errors.New("cache warm-up disabled")
+)
+
+type WarmupOptions struct {
+	// Links is the number of links to preload.
+	Links int
+	// Order picks them by click count or creation time, see
+	// repository.URLOrderClicks and repository.URLOrderRecent.
+	Order string
+	// BatchSize is the number of links read from postgres per query.
+	BatchSize int
+	// Rate caps the links loaded per second; 0 is unlimited.
+	Rate int
+}
+
+// WithWarmup configures StartCacheWarmup.
+func (s *URLService) WithWarmup(opts WarmupOptions) *URLService {
+	s.warmup = opts
+	return s
+}
+
+// StartCacheWarmup preloads the most popular links into the cache in the
+// background, so a cold cache does not send every redirect to postgres at
+// once. Only one warm-up runs at a time. It returns ErrWarmupDisabled when
+// WithWarmup was not called or asked for no links.
+func (s *URLService) StartCacheWarmup(ctx context.Context) error {
+	if s.warmup.Links <= 0 {
+		return ErrWarmupDisabled
+	}
+	if !s.warming.CompareAndSwap(false, true) {
+		return ErrWarmupRunning
+	}
+
+	go func() {
+		defer s.warming.Store(false)
+
+		start := time.Now()
+		loaded, err := s.warmCache(ctx)
+		if err != nil {
+			metrics.CacheWarmupRuns.WithLabelValues("error").Inc()
+			s.logger.Error("cache warm-up", "loaded", loaded, "error", err)
+			return
+		}
+
+		metrics.CacheWarmupRuns.WithLabelValues("ok").Inc()
+		s.logger.Info("cache warm-up finished", "loaded", loaded, "order", s.warmup.Order, "duration", time.Since(start))
+	}()
+
+	return nil
+}
+
+// warmCache reads links page by page and fills the cache, pausing between
+// pages to stay under the configured rate. Entries already cached are left
+// alone.
+func (s *URLService) warmCache(ctx context.Context) (int, error) {
+	opts := s.warmup
+	batch := max(1, min(opts.BatchSize, opts.Links))
+
+	var pause time.Duration
+	if opts.Rate > 0 {
+		pause = time.Duration(batch) * time.Second / time.Duration(opts.Rate)
+	}
+
+	metrics.CacheWarmupProgress.Set(0)
+	loaded := 0
+	var after *repository.URL
+
+	for read := 0; read < opts.Links; {
+		pageStart := time.Now()
+
+		urls, err := s.postgres.GetURLsByPopularity(opts.Order, after, min(batch, opts.Links-read))
+		if err != nil {
+			return loaded, err
+		}
+		if len(urls) > 0 {
+			after = &urls[len(urls)-1]
+		}
+		read += len(urls)
+
+		for _, url := range urls {
+			if s.scanner.Flagged(url.RiskScore) {
+				metrics.CacheWarmupLinks.WithLabelValues("skipped").Inc()
+				continue
+			}
+
+			if err := s.cache.Fill(ctx, url.ShortCode, url.OriginalURL); err != nil {
+				if errors.Is(err, repository.ErrCacheUnavailable) {
+					return loaded, err
+				}
+				metrics.CacheWarmupLinks.WithLabelValues("failed").Inc()
+				s.logCacheError("cache warm-up", url.ShortCode, err)
+				continue
+			}
+			metrics.CacheWarmupLinks.WithLabelValues("loaded").Inc()
+			loaded++
+		}
+
+		metrics.CacheWarmupProgress.Set(float64(read) / float64(opts.Links))
+
+		if len(urls) < batch {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return loaded, ctx.Err()
+		case <-time.After(pause - time.Since(pageStart)):
+		}
+	}
+
+	metrics.CacheWarmupProgress.Set(1)
+	return loaded, nil
+}
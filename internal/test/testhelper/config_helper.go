@@ -34,6 +34,7 @@ func NewTestPostgresConfig() *config.PostgresConfig {
 
 func NewTestRedisConfig() *config.RedisConfig {
 	return &config.RedisConfig{
+		Enabled:  true,
 		Host:     getEnv("TEST_REDIS_HOST", "localhost"),
 		Port:     getEnv("TEST_REDIS_PORT", "6381"),
 		Password: getEnv("TEST_REDIS_PASSWORD", ""),
@@ -83,10 +84,71 @@ func createTestSchema(t *testing.T, db *sql.DB) {
 		original_url TEXT NOT NULL,
 		short_code VARCHAR(10) UNIQUE NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		click_count INTEGER DEFAULT 0
+		click_count BIGINT NOT NULL DEFAULT 0,
+		risk_score INTEGER NOT NULL DEFAULT 0,
+		final_url TEXT,
+		redirect_chain TEXT[],
+		last_status INTEGER,
+		last_checked_at TIMESTAMP WITH TIME ZONE,
+		check_failures INTEGER NOT NULL DEFAULT 0,
+		disabled BOOLEAN NOT NULL DEFAULT FALSE
 	);
 	
 	CREATE INDEX IF NOT EXISTS idx_short_code ON urls(short_code);
+
+	CREATE TABLE IF NOT EXISTS clicks (
+		id BIGSERIAL,
+		short_code VARCHAR(10) NOT NULL,
+		clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		referrer TEXT,
+		referrer_domain TEXT,
+		user_agent TEXT,
+		ip INET,
+		variant TEXT,
+		event_id TEXT,
+		browser TEXT,
+		os TEXT,
+		device_type TEXT,
+		is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+		country TEXT,
+		region TEXT,
+		city TEXT,
+		ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (id, clicked_at)
+	) PARTITION BY RANGE (clicked_at);
+
+	CREATE TABLE IF NOT EXISTS clicks_default PARTITION OF clicks DEFAULT;
+
+	CREATE INDEX IF NOT EXISTS idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_clicks_event_id ON clicks(event_id, clicked_at);
+	CREATE INDEX IF NOT EXISTS idx_clicks_ingested_at ON clicks(ingested_at);
+
+	CREATE TABLE IF NOT EXISTS click_rollups_hourly (
+		short_code VARCHAR(10) NOT NULL,
+		bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+		dimension TEXT NOT NULL,
+		value TEXT NOT NULL,
+		is_bot BOOLEAN NOT NULL,
+		clicks BIGINT NOT NULL,
+		PRIMARY KEY (short_code, bucket, dimension, value, is_bot)
+	);
+
+	CREATE TABLE IF NOT EXISTS click_rollups_daily (
+		short_code VARCHAR(10) NOT NULL,
+		bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+		dimension TEXT NOT NULL,
+		value TEXT NOT NULL,
+		is_bot BOOLEAN NOT NULL,
+		clicks BIGINT NOT NULL,
+		PRIMARY KEY (short_code, bucket, dimension, value, is_bot)
+	);
+
+	CREATE TABLE IF NOT EXISTS rollup_state (
+		name TEXT PRIMARY KEY,
+		watermark TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	INSERT INTO rollup_state (name, watermark) VALUES ('clicks', '1970-01-01 00:00:00+00') ON CONFLICT (name) DO NOTHING;
 	`
 
 	_, err := db.Exec(query)
@@ -0,0 +1,119 @@
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+const (
+	Unknown = "unknown"
+
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+//go:embed rules.json
+var defaultRules []byte
+
+// Info is the classification of a single user agent.
+type Info struct {
+	Browser string
+	OS      string
+	Device  string
+	Bot     bool
+}
+
+type rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	re      *regexp.Regexp
+}
+
+// rules lists patterns per category. Within a category the first matching
+// rule wins, so more specific rules must come first.
+type rules struct {
+	Bots     []rule `json:"bots"`
+	Browsers []rule `json:"browsers"`
+	OS       []rule `json:"os"`
+	Devices  []rule `json:"devices"`
+}
+
+type Classifier struct {
+	rules rules
+}
+
+// New builds a classifier from the rules file at path, or from the embedded
+// rules when path is empty.
+func New(path string) (*Classifier, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("useragent: read rules: %w", err)
+		}
+	}
+
+	return parse(data)
+}
+
+func parse(data []byte) (*Classifier, error) {
+	var r rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("useragent: decode rules: %w", err)
+	}
+
+	for _, category := range []([]rule){r.Bots, r.Browsers, r.OS, r.Devices} {
+		for i := range category {
+			re, err := regexp.Compile("(?i)" + category[i].Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("useragent: rule %q: %w", category[i].Name, err)
+			}
+			category[i].re = re
+		}
+	}
+
+	return &Classifier{rules: r}, nil
+}
+
+// Classify tags a user agent. Empty user agents are treated as bots: real
+// browsers always send one.
+func (c *Classifier) Classify(userAgent string) Info {
+	if userAgent == "" {
+		return Info{Browser: Unknown, OS: Unknown, Device: DeviceBot, Bot: true}
+	}
+
+	if name := match(c.rules.Bots, userAgent); name != "" {
+		return Info{Browser: name, OS: Unknown, Device: DeviceBot, Bot: true}
+	}
+
+	info := Info{
+		Browser: match(c.rules.Browsers, userAgent),
+		OS:      match(c.rules.OS, userAgent),
+		Device:  match(c.rules.Devices, userAgent),
+	}
+
+	if info.Browser == "" {
+		info.Browser = Unknown
+	}
+	if info.OS == "" {
+		info.OS = Unknown
+	}
+	if info.Device == "" {
+		info.Device = DeviceDesktop
+	}
+
+	return info
+}
+
+func match(rules []rule, userAgent string) string {
+	for _, r := range rules {
+		if r.re.MatchString(userAgent) {
+			return r.Name
+		}
+	}
+	return ""
+}
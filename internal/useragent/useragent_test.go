@@ -0,0 +1,80 @@
+package useragent
+
+import "testing"
+
+func TestClassifier_Classify(t *testing.T) {
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("load embedded rules: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ua   string
+		want Info
+	}{
+		{
+			"chrome on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+			Info{Browser: "Chrome", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"edge on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.2792.79",
+			Info{Browser: "Edge", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"safari on iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Safari", OS: "iOS", Device: "mobile"},
+		},
+		{
+			"safari on ipad",
+			"Mozilla/5.0 (iPad; CPU OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Safari", OS: "iOS", Device: "tablet"},
+		},
+		{
+			"chrome on android phone",
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+			Info{Browser: "Chrome", OS: "Android", Device: "mobile"},
+		},
+		{
+			"firefox on linux",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+			Info{Browser: "Firefox", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			"slack unfurler",
+			"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			Info{Browser: "Slackbot", OS: Unknown, Device: DeviceBot, Bot: true},
+		},
+		{
+			"twitterbot",
+			"Twitterbot/1.0",
+			Info{Browser: "Twitterbot", OS: Unknown, Device: DeviceBot, Bot: true},
+		},
+		{
+			"curl",
+			"curl/8.5.0",
+			Info{Browser: "curl", OS: Unknown, Device: DeviceBot, Bot: true},
+		},
+		{
+			"uptime monitor",
+			"Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+			Info{Browser: "UptimeRobot", OS: Unknown, Device: DeviceBot, Bot: true},
+		},
+		{
+			"empty",
+			"",
+			Info{Browser: Unknown, OS: Unknown, Device: DeviceBot, Bot: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.ua); got != tt.want {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
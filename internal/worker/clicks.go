@@ -0,0 +1,247 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+const maxTrackedFailures = 10000
+
+type ClickStream interface {
+	EnsureGroup(ctx context.Context) error
+	Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]repository.StreamMessage, error)
+	Pending(ctx context.Context, minIdle time.Duration, count int64) ([]repository.PendingMessage, error)
+	Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []repository.PendingMessage) ([]repository.StreamMessage, error)
+	Ack(ctx context.Context, ids ...string) error
+	DeadLetter(ctx context.Context, msg repository.StreamMessage, reason string) error
+	Backlog(ctx context.Context) (lag int64, pending int64, err error)
+}
+
+type ClickRepository interface {
+	InsertClicks(clicks []*repository.Click) error
+}
+
+type Options struct {
+	Consumer      string
+	BatchSize     int64
+	Block         time.Duration
+	ClaimInterval time.Duration
+	ClaimMinIdle  time.Duration
+	MaxDeliveries int64
+	RetryBackoff  time.Duration
+}
+
+// ClickConsumer drains the click stream into postgres. Messages are only
+// acknowledged after their batch is committed, so delivery is at least once;
+// the event ID unique index turns redeliveries into no-ops.
+type ClickConsumer struct {
+	stream ClickStream
+	repo   ClickRepository
+	opts   Options
+	logger *slog.Logger
+
+	// failures keeps the last insert error of messages left pending, so the
+	// dead letter records why a message kept failing. It is only used from
+	// the Run goroutine.
+	failures map[string]string
+}
+
+func NewClickConsumer(stream ClickStream, repo ClickRepository, opts Options, logger *slog.Logger) *ClickConsumer {
+	return &ClickConsumer{
+		stream:   stream,
+		repo:     repo,
+		opts:     opts,
+		logger:   logger,
+		failures: make(map[string]string),
+	}
+}
+
+func (c *ClickConsumer) Run(ctx context.Context) error {
+	if err := c.stream.EnsureGroup(ctx); err != nil {
+		return err
+	}
+
+	lastClaim := time.Time{}
+
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= c.opts.ClaimInterval {
+			c.reclaim(ctx)
+			lastClaim = time.Now()
+		}
+
+		messages, err := c.stream.Read(ctx, c.opts.Consumer, c.opts.BatchSize, c.opts.Block)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			c.logger.Error("worker: read", "error", err)
+			c.sleep(ctx, c.opts.RetryBackoff)
+			continue
+		}
+
+		if !c.process(ctx, messages) {
+			c.sleep(ctx, c.opts.RetryBackoff)
+		}
+	}
+
+	return nil
+}
+
+// RunBacklogMetrics periodically exports the consumer group lag.
+func (c *ClickConsumer) RunBacklogMetrics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lag, pending, err := c.stream.Backlog(ctx)
+		if err != nil {
+			c.logger.Error("worker: backlog", "error", err)
+		} else {
+			metrics.ClickStreamLag.Set(float64(lag))
+			metrics.ClickStreamPending.Set(float64(pending))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reclaim takes over messages left unacknowledged by crashed or failing
+// consumers. Messages delivered too many times are dead-lettered.
+func (c *ClickConsumer) reclaim(ctx context.Context) {
+	pending, err := c.stream.Pending(ctx, c.opts.ClaimMinIdle, c.opts.BatchSize)
+	if err != nil {
+		c.logger.Error("worker: pending", "error", err)
+		return
+	}
+
+	messages, err := c.stream.Claim(ctx, c.opts.Consumer, c.opts.ClaimMinIdle, pending)
+	if err != nil {
+		c.logger.Error("worker: claim", "error", err)
+		return
+	}
+
+	if len(messages) > 0 {
+		c.logger.Info("worker: reclaimed messages", "count", len(messages))
+	}
+
+	c.process(ctx, messages)
+}
+
+// process writes a batch and reports whether it made progress. When the batch
+// insert fails the messages are retried one by one, so a single poison event
+// cannot block the rest. Events that still fail are left unacknowledged:
+// reclaim redelivers them and dead-letters them once they exceed
+// MaxDeliveries. If every event fails the database is most likely
+// unavailable and the caller backs off.
+func (c *ClickConsumer) process(ctx context.Context, messages []repository.StreamMessage) bool {
+	var valid []repository.StreamMessage
+
+	for _, msg := range messages {
+		switch {
+		case msg.Err != nil:
+			c.deadLetter(ctx, msg, msg.Err.Error())
+		case msg.Deliveries > c.opts.MaxDeliveries:
+			reason := "too many deliveries"
+			if last, ok := c.failures[msg.ID]; ok {
+				reason += ": " + last
+			}
+			c.deadLetter(ctx, msg, reason)
+		default:
+			valid = append(valid, msg)
+		}
+	}
+
+	if len(valid) == 0 {
+		return true
+	}
+
+	clicks := make([]*repository.Click, 0, len(valid))
+	for _, msg := range valid {
+		clicks = append(clicks, msg.Click)
+	}
+
+	start := time.Now()
+	err := c.repo.InsertClicks(clicks)
+	metrics.ClickBatchDuration.Observe(time.Since(start).Seconds())
+
+	if err == nil {
+		c.ack(ctx, valid)
+		return true
+	}
+
+	c.logger.Warn("worker: batch insert failed, retrying individually", "count", len(valid), "error", err)
+
+	var written []repository.StreamMessage
+	for _, msg := range valid {
+		if err := c.repo.InsertClicks([]*repository.Click{msg.Click}); err != nil {
+			c.recordFailure(msg.ID, err)
+			continue
+		}
+		written = append(written, msg)
+	}
+
+	metrics.ClickEventsFailed.Add(float64(len(valid) - len(written)))
+
+	if len(written) == 0 {
+		return false
+	}
+
+	c.ack(ctx, written)
+	return true
+}
+
+// recordFailure remembers err for a message left pending. Messages claimed
+// by another consumer never come back here, so the map is reset when it grows
+// past a bound instead of leaking their entries.
+func (c *ClickConsumer) recordFailure(id string, err error) {
+	if len(c.failures) >= maxTrackedFailures {
+		clear(c.failures)
+	}
+	c.failures[id] = err.Error()
+}
+
+func (c *ClickConsumer) ack(ctx context.Context, messages []repository.StreamMessage) {
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	if err := c.stream.Ack(ctx, ids...); err != nil {
+		// The rows are committed; the messages will be redelivered and
+		// skipped by the event ID conflict clause.
+		c.logger.Error("worker: ack", "count", len(ids), "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		delete(c.failures, id)
+	}
+
+	metrics.ClickEventsProcessed.Add(float64(len(ids)))
+}
+
+func (c *ClickConsumer) deadLetter(ctx context.Context, msg repository.StreamMessage, reason string) {
+	if err := c.stream.DeadLetter(ctx, msg, reason); err != nil {
+		c.logger.Error("worker: dead-letter", "id", msg.ID, "error", err)
+		return
+	}
+
+	delete(c.failures, msg.ID)
+	metrics.ClickEventsDeadLettered.Inc()
+	c.logger.Warn("worker: dead-lettered message", "id", msg.ID, "deliveries", msg.Deliveries, "reason", reason)
+}
+
+func (c *ClickConsumer) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
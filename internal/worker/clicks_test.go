@@ -0,0 +1,228 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+var errDatabase = errors.New("database unavailable")
+
+type deadLetter struct {
+	id     string
+	reason string
+}
+
+// fakeStream keeps delivered but unacknowledged messages as pending, like a
+// consumer group does.
+type fakeStream struct {
+	pending map[string]repository.StreamMessage
+	acked   []string
+	dead    []deadLetter
+}
+
+func newFakeStream() *fakeStream {
+	return &fakeStream{pending: make(map[string]repository.StreamMessage)}
+}
+
+func (s *fakeStream) deliver(messages ...repository.StreamMessage) []repository.StreamMessage {
+	for i := range messages {
+		messages[i].Deliveries++
+		s.pending[messages[i].ID] = messages[i]
+	}
+	return messages
+}
+
+func (s *fakeStream) EnsureGroup(ctx context.Context) error { return nil }
+
+func (s *fakeStream) Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]repository.StreamMessage, error) {
+	return nil, nil
+}
+
+func (s *fakeStream) Pending(ctx context.Context, minIdle time.Duration, count int64) ([]repository.PendingMessage, error) {
+	var res []repository.PendingMessage
+	for _, msg := range s.pending {
+		res = append(res, repository.PendingMessage{ID: msg.ID, Deliveries: msg.Deliveries})
+	}
+	slices.SortFunc(res, func(a, b repository.PendingMessage) int { return strings.Compare(a.ID, b.ID) })
+	return res, nil
+}
+
+func (s *fakeStream) Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []repository.PendingMessage) ([]repository.StreamMessage, error) {
+	var res []repository.StreamMessage
+	for _, p := range pending {
+		res = append(res, s.pending[p.ID])
+	}
+	return s.deliver(res...), nil
+}
+
+func (s *fakeStream) Ack(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		delete(s.pending, id)
+	}
+	s.acked = append(s.acked, ids...)
+	return nil
+}
+
+func (s *fakeStream) DeadLetter(ctx context.Context, msg repository.StreamMessage, reason string) error {
+	delete(s.pending, msg.ID)
+	s.dead = append(s.dead, deadLetter{id: msg.ID, reason: reason})
+	return nil
+}
+
+func (s *fakeStream) Backlog(ctx context.Context) (int64, int64, error) {
+	return 0, int64(len(s.pending)), nil
+}
+
+// fakeClicks fails batches containing a poisoned short code, and every batch
+// while down is set.
+type fakeClicks struct {
+	poison   map[string]error
+	down     bool
+	inserted []string
+}
+
+func (r *fakeClicks) InsertClicks(clicks []*repository.Click) error {
+	if r.down {
+		return errDatabase
+	}
+	for _, click := range clicks {
+		if err := r.poison[click.ShortCode]; err != nil {
+			return err
+		}
+	}
+	for _, click := range clicks {
+		r.inserted = append(r.inserted, click.ShortCode)
+	}
+	return nil
+}
+
+func newTestConsumer(stream ClickStream, repo ClickRepository) *ClickConsumer {
+	return NewClickConsumer(stream, repo, Options{
+		Consumer:      "test",
+		BatchSize:     10,
+		MaxDeliveries: 3,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func message(id, shortCode string) repository.StreamMessage {
+	return repository.StreamMessage{ID: id, Click: &repository.Click{ShortCode: shortCode}}
+}
+
+func TestClickConsumer_AcksWrittenBatch(t *testing.T) {
+	stream := newFakeStream()
+	repo := &fakeClicks{}
+	c := newTestConsumer(stream, repo)
+
+	if !c.process(context.Background(), stream.deliver(message("1", "a"), message("2", "b"))) {
+		t.Fatal("process reported no progress")
+	}
+
+	if !slices.Equal(stream.acked, []string{"1", "2"}) {
+		t.Errorf("acked = %v, want [1 2]", stream.acked)
+	}
+	if len(stream.pending) != 0 || len(stream.dead) != 0 {
+		t.Errorf("pending = %v, dead = %v, want none", stream.pending, stream.dead)
+	}
+}
+
+func TestClickConsumer_LeavesFailedEventsPending(t *testing.T) {
+	stream := newFakeStream()
+	repo := &fakeClicks{poison: map[string]error{"bad": errors.New("invalid click")}}
+	c := newTestConsumer(stream, repo)
+
+	c.process(context.Background(), stream.deliver(message("1", "a"), message("2", "bad"), message("3", "c")))
+
+	if !slices.Equal(stream.acked, []string{"1", "3"}) {
+		t.Errorf("acked = %v, want [1 3]", stream.acked)
+	}
+	if len(stream.dead) != 0 {
+		t.Errorf("dead-lettered %v on the first delivery", stream.dead)
+	}
+	if _, ok := stream.pending["2"]; !ok {
+		t.Error("failed event was not left pending")
+	}
+}
+
+func TestClickConsumer_DatabaseDownAcksNothing(t *testing.T) {
+	stream := newFakeStream()
+	repo := &fakeClicks{down: true}
+	c := newTestConsumer(stream, repo)
+
+	if c.process(context.Background(), stream.deliver(message("1", "a"), message("2", "b"))) {
+		t.Error("process reported progress with the database down")
+	}
+	if len(stream.acked) != 0 || len(stream.dead) != 0 {
+		t.Errorf("acked = %v, dead = %v, want none", stream.acked, stream.dead)
+	}
+	if len(stream.pending) != 2 {
+		t.Errorf("pending = %d, want 2", len(stream.pending))
+	}
+}
+
+func TestClickConsumer_ReclaimRetriesTransientFailures(t *testing.T) {
+	stream := newFakeStream()
+	repo := &fakeClicks{poison: map[string]error{"b": errDatabase}}
+	c := newTestConsumer(stream, repo)
+
+	c.process(context.Background(), stream.deliver(message("1", "a"), message("2", "b")))
+
+	// The database recovers before the event is redelivered.
+	delete(repo.poison, "b")
+	c.reclaim(context.Background())
+
+	if !slices.Equal(stream.acked, []string{"1", "2"}) {
+		t.Errorf("acked = %v, want [1 2]", stream.acked)
+	}
+	if len(stream.dead) != 0 {
+		t.Errorf("dead-lettered %v after a transient failure", stream.dead)
+	}
+	if len(c.failures) != 0 {
+		t.Errorf("failures not cleared after ack: %v", c.failures)
+	}
+}
+
+func TestClickConsumer_DeadLettersAfterMaxDeliveries(t *testing.T) {
+	stream := newFakeStream()
+	repo := &fakeClicks{poison: map[string]error{"bad": errors.New("invalid click")}}
+	c := newTestConsumer(stream, repo)
+
+	c.process(context.Background(), stream.deliver(message("1", "a"), message("2", "bad")))
+
+	// Deliveries 2 and 3 fail again; the fourth exceeds MaxDeliveries.
+	for i := 0; i < 3; i++ {
+		if len(stream.dead) != 0 {
+			t.Fatalf("dead-lettered after %d deliveries", i+1)
+		}
+		c.reclaim(context.Background())
+	}
+
+	if len(stream.dead) != 1 || stream.dead[0].id != "2" {
+		t.Fatalf("dead = %v, want message 2", stream.dead)
+	}
+	if !strings.Contains(stream.dead[0].reason, "invalid click") {
+		t.Errorf("reason = %q, want the message's own error", stream.dead[0].reason)
+	}
+	if len(stream.pending) != 0 {
+		t.Errorf("pending = %v, want none", stream.pending)
+	}
+}
+
+func TestClickConsumer_DeadLettersUndecodableEvents(t *testing.T) {
+	stream := newFakeStream()
+	c := newTestConsumer(stream, &fakeClicks{})
+
+	msg := repository.StreamMessage{ID: "1", Err: errors.New("bad payload")}
+	c.process(context.Background(), stream.deliver(msg))
+
+	if len(stream.dead) != 1 || stream.dead[0].reason != "bad payload" {
+		t.Errorf("dead = %v, want message 1 with its decode error", stream.dead)
+	}
+}
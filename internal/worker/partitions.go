@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type PartitionRepository interface {
+	ListClickPartitions() ([]repository.ClickPartition, error)
+	CreateClickPartition(p repository.ClickPartition) error
+	DropClickPartition(p repository.ClickPartition) error
+	ArchiveClickPartition(p repository.ClickPartition) (string, error)
+	CountDefaultClicksBefore(before time.Time) (int64, error)
+	ExpireDefaultClicks(before time.Time, archive bool) (int64, error)
+}
+
+type PartitionOptions struct {
+	// Ahead is the number of months after the current one to create
+	// partitions for.
+	Ahead int
+	// Retention is the number of whole months of clicks to keep besides the
+	// current one; 0 keeps everything.
+	Retention int
+	// Archive detaches expired partitions and keeps them as standalone
+	// tables instead of dropping them.
+	Archive bool
+	// DryRun reports the changes without making them.
+	DryRun bool
+}
+
+type PartitionReport struct {
+	Created  []string
+	Dropped  []string
+	Archived []string
+	// DefaultExpired is the number of expired clicks removed from, or in a
+	// dry run found in, the default partition.
+	DefaultExpired int64
+}
+
+// PartitionMaintainer keeps the monthly partitions of the clicks table in
+// shape: upcoming months exist before clicks arrive for them, and months
+// past the retention period are dropped or archived.
+type PartitionMaintainer struct {
+	repo   PartitionRepository
+	opts   PartitionOptions
+	logger *slog.Logger
+}
+
+func NewPartitionMaintainer(repo PartitionRepository, opts PartitionOptions, logger *slog.Logger) *PartitionMaintainer {
+	return &PartitionMaintainer{
+		repo:   repo,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+func (m *PartitionMaintainer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.RunOnce(time.Now()); err != nil {
+			m.logger.Error("worker: partitions", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *PartitionMaintainer) RunOnce(now time.Time) (*PartitionReport, error) {
+	report, err := m.maintain(now)
+	if err != nil {
+		metrics.ClickPartitionRuns.WithLabelValues("error").Inc()
+		return report, err
+	}
+
+	metrics.ClickPartitionRuns.WithLabelValues("ok").Inc()
+	if len(report.Created)+len(report.Dropped)+len(report.Archived) > 0 || report.DefaultExpired > 0 {
+		m.logger.Info("worker: click partitions maintained", "created", report.Created,
+			"dropped", report.Dropped, "archived", report.Archived,
+			"default_expired", report.DefaultExpired, "dry_run", m.opts.DryRun)
+	}
+	return report, nil
+}
+
+func (m *PartitionMaintainer) maintain(now time.Time) (*PartitionReport, error) {
+	report := &PartitionReport{}
+
+	existing, err := m.repo.ListClickPartitions()
+	if err != nil {
+		return report, err
+	}
+
+	attached := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		attached[p.Name] = true
+	}
+
+	current := repository.ClickPartitionFor(now.UTC())
+	for i := 0; i <= m.opts.Ahead; i++ {
+		p := repository.ClickPartitionFor(current.Month.AddDate(0, i, 0))
+		if attached[p.Name] {
+			continue
+		}
+		if !m.opts.DryRun {
+			if err := m.repo.CreateClickPartition(p); err != nil {
+				return report, err
+			}
+		}
+		report.Created = append(report.Created, p.Name)
+	}
+
+	if m.opts.Retention <= 0 {
+		return report, nil
+	}
+
+	cutoff := current.Month.AddDate(0, -m.opts.Retention, 0)
+	for _, p := range existing {
+		if !p.Month.Before(cutoff) {
+			continue
+		}
+
+		if m.opts.Archive {
+			if !m.opts.DryRun {
+				if _, err := m.repo.ArchiveClickPartition(p); err != nil {
+					return report, err
+				}
+			}
+			report.Archived = append(report.Archived, p.Name)
+			continue
+		}
+
+		if !m.opts.DryRun {
+			if err := m.repo.DropClickPartition(p); err != nil {
+				return report, err
+			}
+		}
+		report.Dropped = append(report.Dropped, p.Name)
+	}
+
+	// Clicks that landed in the default partition, e.g. while the maintainer
+	// was not running, expire with the same cutoff.
+	if m.opts.DryRun {
+		report.DefaultExpired, err = m.repo.CountDefaultClicksBefore(cutoff)
+	} else {
+		report.DefaultExpired, err = m.repo.ExpireDefaultClicks(cutoff, m.opts.Archive)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type fakePartitions struct {
+	existing []repository.ClickPartition
+	created  []string
+	dropped  []string
+	archived []string
+
+	// defaultClicks holds the click times in the default partition.
+	defaultClicks  []time.Time
+	archivedClicks int
+}
+
+func (r *fakePartitions) ListClickPartitions() ([]repository.ClickPartition, error) {
+	return r.existing, nil
+}
+
+func (r *fakePartitions) CreateClickPartition(p repository.ClickPartition) error {
+	r.created = append(r.created, p.Name)
+	return nil
+}
+
+func (r *fakePartitions) DropClickPartition(p repository.ClickPartition) error {
+	r.dropped = append(r.dropped, p.Name)
+	return nil
+}
+
+func (r *fakePartitions) ArchiveClickPartition(p repository.ClickPartition) (string, error) {
+	r.archived = append(r.archived, p.Name)
+	return "archive_" + p.Name, nil
+}
+
+func (r *fakePartitions) CountDefaultClicksBefore(before time.Time) (int64, error) {
+	var n int64
+	for _, at := range r.defaultClicks {
+		if at.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakePartitions) ExpireDefaultClicks(before time.Time, archive bool) (int64, error) {
+	var kept []time.Time
+	var removed int64
+	for _, at := range r.defaultClicks {
+		if at.Before(before) {
+			removed++
+			continue
+		}
+		kept = append(kept, at)
+	}
+	r.defaultClicks = kept
+	if archive {
+		r.archivedClicks += int(removed)
+	}
+	return removed, nil
+}
+
+func partitionsFor(months ...string) []repository.ClickPartition {
+	var res []repository.ClickPartition
+	for _, m := range months {
+		month, err := time.Parse("2006-01", m)
+		if err != nil {
+			panic(err)
+		}
+		res = append(res, repository.ClickPartitionFor(month))
+	}
+	return res
+}
+
+func names(partitions []repository.ClickPartition) []string {
+	var res []string
+	for _, p := range partitions {
+		res = append(res, p.Name)
+	}
+	return res
+}
+
+func newTestMaintainer(repo PartitionRepository, opts PartitionOptions) *PartitionMaintainer {
+	return NewPartitionMaintainer(repo, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+var partitionNow = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func TestPartitionMaintainer_CreatesMonthsAhead(t *testing.T) {
+	repo := &fakePartitions{existing: partitionsFor("2026-02", "2026-03", "2026-04")}
+
+	report, err := newTestMaintainer(repo, PartitionOptions{Ahead: 3}).RunOnce(partitionNow)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	want := names(partitionsFor("2026-05", "2026-06"))
+	if !slices.Equal(repo.created, want) || !slices.Equal(report.Created, want) {
+		t.Errorf("created = %v, reported %v, want %v", repo.created, report.Created, want)
+	}
+	if len(repo.dropped)+len(repo.archived) != 0 || report.DefaultExpired != 0 {
+		t.Errorf("retention applied with Retention 0: %+v", report)
+	}
+}
+
+func TestPartitionMaintainer_DropsExpiredMonths(t *testing.T) {
+	repo := &fakePartitions{
+		existing: partitionsFor("2025-11", "2025-12", "2026-01", "2026-02", "2026-03"),
+		defaultClicks: []time.Time{
+			time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC),
+			time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC),
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// Two months besides the current one are kept: January and February.
+	report, err := newTestMaintainer(repo, PartitionOptions{Retention: 2}).RunOnce(partitionNow)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	want := names(partitionsFor("2025-11", "2025-12"))
+	if !slices.Equal(repo.dropped, want) || !slices.Equal(report.Dropped, want) {
+		t.Errorf("dropped = %v, reported %v, want %v", repo.dropped, report.Dropped, want)
+	}
+	if len(repo.archived) != 0 {
+		t.Errorf("archived %v in drop mode", repo.archived)
+	}
+	if report.DefaultExpired != 2 || len(repo.defaultClicks) != 1 || repo.archivedClicks != 0 {
+		t.Errorf("default partition: expired %d, kept %v, archived %d; want 2 deleted and January kept",
+			report.DefaultExpired, repo.defaultClicks, repo.archivedClicks)
+	}
+}
+
+func TestPartitionMaintainer_ArchivesExpiredMonths(t *testing.T) {
+	repo := &fakePartitions{
+		existing:      partitionsFor("2025-12", "2026-01", "2026-02", "2026-03"),
+		defaultClicks: []time.Time{time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	report, err := newTestMaintainer(repo, PartitionOptions{Retention: 2, Archive: true}).RunOnce(partitionNow)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	want := names(partitionsFor("2025-12"))
+	if !slices.Equal(repo.archived, want) || !slices.Equal(report.Archived, want) {
+		t.Errorf("archived = %v, reported %v, want %v", repo.archived, report.Archived, want)
+	}
+	if len(repo.dropped) != 0 {
+		t.Errorf("dropped %v in archive mode", repo.dropped)
+	}
+	if report.DefaultExpired != 1 || repo.archivedClicks != 1 {
+		t.Errorf("default partition: expired %d, archived %d, want 1 archived", report.DefaultExpired, repo.archivedClicks)
+	}
+}
+
+func TestPartitionMaintainer_DryRunChangesNothing(t *testing.T) {
+	repo := &fakePartitions{
+		existing:      partitionsFor("2025-12", "2026-03"),
+		defaultClicks: []time.Time{time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	report, err := newTestMaintainer(repo, PartitionOptions{Ahead: 1, Retention: 2, DryRun: true}).RunOnce(partitionNow)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if len(repo.created)+len(repo.dropped)+len(repo.archived) != 0 || len(repo.defaultClicks) != 1 {
+		t.Errorf("dry run changed partitions: created %v, dropped %v, archived %v, default %v",
+			repo.created, repo.dropped, repo.archived, repo.defaultClicks)
+	}
+
+	if !slices.Equal(report.Created, names(partitionsFor("2026-04"))) ||
+		!slices.Equal(report.Dropped, names(partitionsFor("2025-12"))) ||
+		report.DefaultExpired != 1 {
+		t.Errorf("dry run report = %+v, want April created, December dropped, 1 default click expired", report)
+	}
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Vadim-Makhnev/url-shortener/internal/metrics"
+	"github.com/Vadim-Makhnev/url-shortener/internal/repository"
+)
+
+type RollupRepository interface {
+	Rollup(settle time.Duration) (*repository.RollupResult, error)
+}
+
+// RollupJob periodically folds newly ingested clicks into the hourly and
+// daily rollups read by the stats API. Several workers may run it; the
+// repository serializes runs.
+type RollupJob struct {
+	repo     RollupRepository
+	interval time.Duration
+	settle   time.Duration
+	logger   *slog.Logger
+}
+
+func NewRollupJob(repo RollupRepository, interval, settle time.Duration, logger *slog.Logger) *RollupJob {
+	return &RollupJob{
+		repo:     repo,
+		interval: interval,
+		settle:   settle,
+		logger:   logger,
+	}
+}
+
+func (j *RollupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(); err != nil {
+			j.logger.Error("worker: rollup", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *RollupJob) RunOnce() error {
+	start := time.Now()
+
+	res, err := j.repo.Rollup(j.settle)
+	if err != nil {
+		metrics.RollupRuns.WithLabelValues("error").Inc()
+		return err
+	}
+
+	metrics.RollupRuns.WithLabelValues("ok").Inc()
+	metrics.RollupBuckets.WithLabelValues("hour").Add(float64(res.Hours))
+	metrics.RollupBuckets.WithLabelValues("day").Add(float64(res.Days))
+	metrics.RollupLag.Set(time.Since(res.Watermark).Seconds())
+
+	if res.Hours > 0 {
+		j.logger.Info("worker: rolled up clicks", "hours", res.Hours, "days", res.Days, "duration", time.Since(start))
+	}
+	return nil
+}
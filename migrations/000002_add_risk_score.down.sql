@@ -0,0 +1 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS risk_score;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS risk_score INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS final_url,
+    DROP COLUMN IF EXISTS redirect_chain;
\ No newline at end of file
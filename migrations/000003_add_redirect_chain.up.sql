@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS final_url TEXT,
+    ADD COLUMN IF NOT EXISTS redirect_chain TEXT[];
\ No newline at end of file
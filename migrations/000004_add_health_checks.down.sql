@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_last_checked_at;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS last_status,
+    DROP COLUMN IF EXISTS last_checked_at,
+    DROP COLUMN IF EXISTS check_failures,
+    DROP COLUMN IF EXISTS disabled;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS last_status INTEGER,
+    ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS check_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_last_checked_at ON urls(last_checked_at);
\ No newline at end of file
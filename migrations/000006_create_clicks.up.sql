@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(10) NOT NULL,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    referrer TEXT,
+    referrer_domain TEXT,
+    user_agent TEXT,
+    ip INET,
+    variant TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_clicks_event_id;
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS event_id;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS event_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clicks_event_id ON clicks(event_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS browser TEXT,
+    ADD COLUMN IF NOT EXISTS os TEXT,
+    ADD COLUMN IF NOT EXISTS device_type TEXT,
+    ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS region,
+    DROP COLUMN IF EXISTS city;
\ No newline at end of file
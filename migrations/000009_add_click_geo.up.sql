@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS country TEXT,
+    ADD COLUMN IF NOT EXISTS region TEXT,
+    ADD COLUMN IF NOT EXISTS city TEXT;
\ No newline at end of file
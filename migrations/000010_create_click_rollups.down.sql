@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS rollup_state;
+DROP TABLE IF EXISTS click_rollups_daily;
+DROP TABLE IF EXISTS click_rollups_hourly;
+
+DROP INDEX IF EXISTS idx_clicks_ingested_at;
+ALTER TABLE clicks DROP COLUMN IF EXISTS ingested_at;
\ No newline at end of file
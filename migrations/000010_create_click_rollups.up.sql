@@ -0,0 +1,30 @@
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_clicks_ingested_at ON clicks(ingested_at);
+
+CREATE TABLE IF NOT EXISTS click_rollups_hourly (
+    short_code VARCHAR(10) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    dimension TEXT NOT NULL,
+    value TEXT NOT NULL,
+    is_bot BOOLEAN NOT NULL,
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_code, bucket, dimension, value, is_bot)
+);
+
+CREATE TABLE IF NOT EXISTS click_rollups_daily (
+    short_code VARCHAR(10) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    dimension TEXT NOT NULL,
+    value TEXT NOT NULL,
+    is_bot BOOLEAN NOT NULL,
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (short_code, bucket, dimension, value, is_bot)
+);
+
+CREATE TABLE IF NOT EXISTS rollup_state (
+    name TEXT PRIMARY KEY,
+    watermark TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+INSERT INTO rollup_state (name, watermark) VALUES ('clicks', '1970-01-01 00:00:00+00') ON CONFLICT (name) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,46 @@
+DO $$
+BEGIN
+    IF to_regclass('clicks') IS NULL
+        OR NOT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'clicks'::regclass) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE clicks RENAME TO clicks_partitioned;
+    ALTER INDEX IF EXISTS clicks_pkey RENAME TO clicks_partitioned_pkey;
+    ALTER INDEX IF EXISTS idx_clicks_short_code_clicked_at RENAME TO idx_clicks_partitioned_short_code_clicked_at;
+    ALTER INDEX IF EXISTS idx_clicks_event_id RENAME TO idx_clicks_partitioned_event_id;
+    ALTER INDEX IF EXISTS idx_clicks_ingested_at RENAME TO idx_clicks_partitioned_ingested_at;
+    ALTER SEQUENCE clicks_id_seq OWNED BY NONE;
+
+    CREATE TABLE clicks (
+        id BIGINT PRIMARY KEY DEFAULT nextval('clicks_id_seq'),
+        short_code VARCHAR(10) NOT NULL,
+        clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+        referrer TEXT,
+        referrer_domain TEXT,
+        user_agent TEXT,
+        ip INET,
+        variant TEXT,
+        event_id TEXT,
+        browser TEXT,
+        os TEXT,
+        device_type TEXT,
+        is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+        country TEXT,
+        region TEXT,
+        city TEXT,
+        ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    );
+
+    ALTER SEQUENCE clicks_id_seq OWNED BY clicks.id;
+
+    INSERT INTO clicks SELECT id, short_code, clicked_at, referrer, referrer_domain, user_agent, ip, variant, event_id,
+        browser, os, device_type, is_bot, country, region, city, ingested_at
+    FROM clicks_partitioned;
+
+    DROP TABLE clicks_partitioned;
+
+    CREATE INDEX idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
+    CREATE UNIQUE INDEX idx_clicks_event_id ON clicks(event_id);
+    CREATE INDEX idx_clicks_ingested_at ON clicks(ingested_at);
+END $$;
\ No newline at end of file
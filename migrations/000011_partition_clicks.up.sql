@@ -0,0 +1,66 @@
+DO $$
+DECLARE
+    month DATE;
+    last_month DATE;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'clicks'::regclass) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE clicks RENAME TO clicks_legacy;
+    ALTER INDEX IF EXISTS clicks_pkey RENAME TO clicks_legacy_pkey;
+    ALTER INDEX IF EXISTS idx_clicks_short_code_clicked_at RENAME TO idx_clicks_legacy_short_code_clicked_at;
+    ALTER INDEX IF EXISTS idx_clicks_event_id RENAME TO idx_clicks_legacy_event_id;
+    ALTER INDEX IF EXISTS idx_clicks_ingested_at RENAME TO idx_clicks_legacy_ingested_at;
+    ALTER SEQUENCE clicks_id_seq OWNED BY NONE;
+
+    CREATE TABLE clicks (
+        id BIGINT NOT NULL DEFAULT nextval('clicks_id_seq'),
+        short_code VARCHAR(10) NOT NULL,
+        clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+        referrer TEXT,
+        referrer_domain TEXT,
+        user_agent TEXT,
+        ip INET,
+        variant TEXT,
+        event_id TEXT,
+        browser TEXT,
+        os TEXT,
+        device_type TEXT,
+        is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+        country TEXT,
+        region TEXT,
+        city TEXT,
+        ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (id, clicked_at)
+    ) PARTITION BY RANGE (clicked_at);
+
+    ALTER SEQUENCE clicks_id_seq OWNED BY clicks.id;
+
+    CREATE INDEX idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
+    CREATE UNIQUE INDEX idx_clicks_event_id ON clicks(event_id, clicked_at);
+    CREATE INDEX idx_clicks_ingested_at ON clicks(ingested_at);
+
+    -- Catches clicks outside the monthly partitions, e.g. before the
+    -- maintenance task has created the partition for a new month.
+    CREATE TABLE clicks_default PARTITION OF clicks DEFAULT;
+
+    month := date_trunc('month', COALESCE((SELECT MIN(clicked_at) FROM clicks_legacy), NOW()) AT TIME ZONE 'UTC')::DATE;
+    last_month := (date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '3 months')::DATE;
+
+    WHILE month <= last_month LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF clicks FOR VALUES FROM (%L) TO (%L)',
+            'clicks_p' || to_char(month, 'YYYYMM'),
+            month::TIMESTAMP AT TIME ZONE 'UTC',
+            (month + INTERVAL '1 month')::TIMESTAMP AT TIME ZONE 'UTC');
+        month := (month + INTERVAL '1 month')::DATE;
+    END LOOP;
+
+    INSERT INTO clicks (id, short_code, clicked_at, referrer, referrer_domain, user_agent, ip, variant, event_id,
+        browser, os, device_type, is_bot, country, region, city, ingested_at)
+    SELECT id, short_code, clicked_at, referrer, referrer_domain, user_agent, ip, variant, event_id,
+        browser, os, device_type, is_bot, country, region, city, ingested_at
+    FROM clicks_legacy;
+
+    DROP TABLE clicks_legacy;
+END $$;
\ No newline at end of file